@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/semihbkgr/yamldiff/compare"
+	"github.com/spf13/cobra"
+)
+
+// compareDirs compares the yaml files with the same relative paths in the directories, printing the path of each file
+// before its differences, and lists the files that exist in only one of the directories.
+// The files are compared by up to the number of jobs in parallel, while their output is printed in the order of their paths,
+// each one preceded by its progress on stderr if it is requested.
+// It reports whether there is any difference, including a file that exists on one side only.
+func compareDirs(cmd *cobra.Command, leftDir, rightDir string, opts compare.DiffOptions) (bool, error) {
+	leftFiles, err := yamlFiles(leftDir)
+	if err != nil {
+		return false, err
+	}
+	rightFiles, err := yamlFiles(rightDir)
+	if err != nil {
+		return false, err
+	}
+
+	names := mergeFileNames(leftFiles, rightFiles)
+	comparisons := compareFiles(names, leftDir, rightDir, leftFiles, rightFiles, opts)
+
+	showProgress := progress == "always" || (progress == "auto" && isTerminal(cmd.ErrOrStderr()))
+	total := 0
+	for _, comparison := range comparisons {
+		if comparison != nil {
+			total++
+		}
+	}
+	compared := 0
+
+	differs := false
+	for i, name := range names {
+		switch {
+		case !leftFiles[name]:
+			differs = true
+			if !quiet {
+				fmt.Fprintf(cmd.OutOrStdout(), "# only in %s: %s\n", rightDir, name)
+			}
+			continue
+		case !rightFiles[name]:
+			differs = true
+			if !quiet {
+				fmt.Fprintf(cmd.OutOrStdout(), "# only in %s: %s\n", leftDir, name)
+			}
+			continue
+		}
+
+		if showProgress {
+			compared++
+			fmt.Fprintf(cmd.ErrOrStderr(), "[%d/%d] comparing %s\n", compared, total, name)
+		}
+		comparison := <-comparisons[i]
+		if _, err := comparison.out.WriteTo(cmd.OutOrStdout()); err != nil {
+			return false, err
+		}
+		if _, err := comparison.errOut.WriteTo(cmd.ErrOrStderr()); err != nil {
+			return false, err
+		}
+		if comparison.err != nil {
+			return false, comparison.err
+		}
+		differs = differs || comparison.differs
+	}
+	return differs, nil
+}
+
+// fileComparison is the outcome of comparing a file that exists in both directories, whose output is buffered
+// until the output of the files before it is printed.
+type fileComparison struct {
+	out     bytes.Buffer
+	errOut  bytes.Buffer
+	differs bool
+	err     error
+}
+
+// compareFiles starts comparing the files that exist in both directories by up to the number of jobs in parallel,
+// returning a channel per file that receives the outcome of its comparison, or nil for a file that exists on one side only.
+// As each file is a unit of work, the documents of a file are compared sequentially when the files are compared in parallel.
+func compareFiles(names []string, leftDir, rightDir string, leftFiles, rightFiles map[string]bool, opts compare.DiffOptions) []chan *fileComparison {
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	if jobs > 1 {
+		opts.Jobs = 1
+	}
+
+	comparisons := make([]chan *fileComparison, len(names))
+	for i, name := range names {
+		if leftFiles[name] && rightFiles[name] {
+			comparisons[i] = make(chan *fileComparison, 1)
+		}
+	}
+	// The files are started in their order, so the ones that are printed first are compared first.
+	go func() {
+		sem := make(chan struct{}, jobs)
+		for i, name := range names {
+			if comparisons[i] == nil {
+				continue
+			}
+			sem <- struct{}{}
+			go func(name string, result chan<- *fileComparison) {
+				defer func() { <-sem }()
+				result <- compareFile(name, filepath.Join(leftDir, name), filepath.Join(rightDir, name), opts)
+			}(name, comparisons[i])
+		}
+	}()
+	return comparisons
+}
+
+// compareFile compares the files at the paths, buffering their output under the header of the name.
+func compareFile(name, leftPath, rightPath string, opts compare.DiffOptions) *fileComparison {
+	comparison := &fileComparison{}
+	left, err := os.ReadFile(leftPath)
+	if err != nil {
+		comparison.err = err
+		return comparison
+	}
+	right, err := os.ReadFile(rightPath)
+	if err != nil {
+		comparison.err = err
+		return comparison
+	}
+	comparison.differs, err = compareInputs(&comparison.out, &comparison.errOut, name, leftPath, rightPath, left, right, opts)
+	if err != nil {
+		comparison.err = fmt.Errorf("%s: %w", name, err)
+	}
+	return comparison
+}
+
+// yamlFiles returns the paths of the files with the .yaml or .yml extension under the directory, relative to it.
+func yamlFiles(dir string) (map[string]bool, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	files := make(map[string]bool)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(path); d.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[name] = true
+		return nil
+	})
+	return files, err
+}
+
+// isTerminal reports whether the writer is a terminal, such as the stderr of an interactive shell.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// mergeFileNames returns the names of the files on either side in sorted order.
+func mergeFileNames(left, right map[string]bool) []string {
+	names := make([]string, 0, len(left)+len(right))
+	for name := range left {
+		names = append(names, name)
+	}
+	for name := range right {
+		if !left[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
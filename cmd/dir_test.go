@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecursive(t *testing.T) {
+	left := t.TempDir()
+	right := t.TempDir()
+	files := map[string]string{
+		filepath.Join(left, "app.yaml"):            "replicas: 1\n",
+		filepath.Join(right, "app.yaml"):           "replicas: 2\n",
+		filepath.Join(left, "same.yaml"):           "name: same\n",
+		filepath.Join(right, "same.yaml"):          "name: same\n",
+		filepath.Join(left, "db.yaml"):             "name: db\n",
+		filepath.Join(right, "cache", "redis.yml"): "name: redis\n",
+		filepath.Join(right, "README.md"):          "not yaml\n",
+	}
+	for path, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	output, err := execute(t, "--plain", "--exit", "--recursive", left, right)
+	assert.EqualError(t, err, "yaml files have difference(s)")
+	expected := fmt.Sprintf("# app.yaml\n~ replicas: 1 -> 2\n# only in %s: %s\n# only in %s: db.yaml\n", right, filepath.Join("cache", "redis.yml"), left)
+	// The error and the usage follow the differences in the output.
+	assert.True(t, strings.HasPrefix(output, expected), output)
+
+	_, err = execute(t, "--recursive", filepath.Join(left, "app.yaml"), right)
+	assert.EqualError(t, err, filepath.Join(left, "app.yaml")+" is not a directory")
+}
+
+func TestRecursiveJobs(t *testing.T) {
+	left := t.TempDir()
+	right := t.TempDir()
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("app-%02d.yaml", i)
+		assert.NoError(t, os.WriteFile(filepath.Join(left, name), []byte(fmt.Sprintf("index: %d\nname: app\nname: app\n", i)), 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(right, name), []byte(fmt.Sprintf("index: %d\nname: app\n", i%3)), 0o644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(left, "only.yaml"), []byte("a: 1\n"), 0o644))
+
+	sequential, err := execute(t, "--plain", "--recursive", "--jobs", "1", left, right)
+	assert.NoError(t, err)
+	assert.Contains(t, sequential, "# app-19.yaml\n~ index: 19 -> 1\nwarning: duplicate key name")
+	for _, jobs := range []string{"4", "0"} {
+		parallel, err := execute(t, "--plain", "--recursive", "--jobs", jobs, left, right)
+		assert.NoError(t, err)
+		assert.Equal(t, sequential, parallel, jobs)
+	}
+}
+
+func TestRecursiveProgress(t *testing.T) {
+	left := t.TempDir()
+	right := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(left, name), []byte("name: app\n"), 0o644))
+		assert.NoError(t, os.WriteFile(filepath.Join(right, name), []byte("name: app\n"), 0o644))
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(right, "c.yaml"), []byte("name: app\n"), 0o644))
+
+	output, err := execute(t, "--plain", "--recursive", "--progress=always", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("[1/2] comparing a.yaml\n[2/2] comparing b.yaml\n# only in %s: c.yaml\n", right), output)
+
+	// The progress is only printed to a terminal, unless it is forced.
+	output, err = execute(t, "--plain", "--recursive", "--progress", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("# only in %s: c.yaml\n", right), output)
+
+	_, err = execute(t, "--progress", left, right)
+	assert.EqualError(t, err, "flag --progress requires --recursive, as the progress is reported per file")
+	_, err = execute(t, "--recursive", "--progress=sometimes", left, right)
+	assert.EqualError(t, err, `invalid progress "sometimes": must be one of auto, always, never`)
+}
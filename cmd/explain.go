@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/semihbkgr/yamldiff/compare"
+	"github.com/spf13/cobra"
+)
+
+var explainCmd = &cobra.Command{
+	Use:                   "explain [flags] <path> <file-left> <file-right>",
+	Short:                 "explain how the nodes at a path compare in two yaml files",
+	Args:                  cobra.ExactArgs(3),
+	DisableFlagsInUseLine: true,
+	RunE:                  runExplain,
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	left, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	right, err := os.ReadFile(args[2])
+	if err != nil {
+		return err
+	}
+
+	explanations, err := compare.Explain(left, right, args[0], enableComments, diffOptions)
+	if err != nil {
+		return err
+	}
+
+	outputs := make([]string, 0, len(explanations))
+	differs := false
+	for _, e := range explanations {
+		outputs = append(outputs, e.Format(formatOptions))
+		differs = differs || len(e.Diffs) > 0
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", strings.Join(outputs, "\n---\n"))
+
+	if exitOnDifference && differs {
+		return errors.New("yaml files have difference(s) at the path")
+	}
+
+	return nil
+}
+
+func init() {
+	explainCmd.Flags().BoolVarP(&exitOnDifference, "exit", "e", false, "Exit with a non-zero status code if the nodes at the path differ.")
+	explainCmd.Flags().BoolVarP(&diffOptions.IgnoreSeqOrder, "unordered", "u", diffOptions.IgnoreSeqOrder, "Ignore the order of items in arrays during comparison.")
+	explainCmd.Flags().BoolVar(&diffOptions.IgnoreKeyCase, "ignore-key-case", diffOptions.IgnoreKeyCase, "Match the keys of maps regardless of their case.")
+	explainCmd.Flags().BoolVarP(&formatOptions.Plain, "plain", "p", formatOptions.Plain, "Output without any color formatting.")
+	explainCmd.Flags().BoolVarP(&enableComments, "comment", "c", enableComments, "Include comments in the output when available.")
+	rootCmd.AddCommand(explainCmd)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/semihbkgr/yamldiff/compare"
+	"github.com/spf13/cobra"
+)
+
+var reviewPageSize = 10
+
+var reviewCmd = &cobra.Command{
+	Use:   "review [flags] <file-left> <file-right>",
+	Short: "review the differences of two yaml files one by one",
+	Long: `Review the differences of two yaml files one by one, page by page.
+
+The current difference is marked with '>', and the page follows it.
+After each page, a command is read from the input, followed by enter:
+  j          next difference
+  k          previous difference
+  n, enter   next page, or quit on the last page
+  p          previous page
+  g          first difference
+  G          last difference
+  q          quit`,
+	Args:                  cobra.ExactArgs(2),
+	DisableFlagsInUseLine: true,
+	RunE:                  runReview,
+}
+
+func runReview(cmd *cobra.Command, args []string) error {
+	if reviewPageSize < 1 {
+		return fmt.Errorf("invalid page size %d: must be at least 1", reviewPageSize)
+	}
+
+	diffs, err := compare.CompareFile(args[0], args[1], enableComments, diffOptions)
+	if err != nil {
+		return err
+	}
+	if sortOutput {
+		diffs.SortByPath()
+	}
+
+	entries := make([]string, 0)
+	for i, docDiffs := range diffs {
+		for _, diff := range docDiffs {
+			entry := diff.Format(formatOptions)
+			if len(diffs) > 1 {
+				entry = fmt.Sprintf("[doc %d] %s", i+1, entry)
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	p := &pager{entries: entries, pageSize: reviewPageSize}
+	return p.run(cmd.InOrStdin(), cmd.OutOrStdout())
+}
+
+// pager shows the entries page by page, reading the command to navigate between the entries or the pages after each one.
+// The current entry is marked, and the page that holds it is shown, so moving to another page moves to its first entry.
+// It is read-only, and it ends when the input is closed or the quit command is read.
+type pager struct {
+	entries  []string
+	pageSize int
+	current  int
+}
+
+func (p *pager) pages() int {
+	return max(1, (len(p.entries)+p.pageSize-1)/p.pageSize)
+}
+
+func (p *pager) page() int {
+	return p.current / p.pageSize
+}
+
+// turn moves to the first entry of the page.
+func (p *pager) turn(page int) {
+	p.current = page * p.pageSize
+}
+
+func (p *pager) run(in io.Reader, out io.Writer) error {
+	if len(p.entries) == 0 {
+		_, err := fmt.Fprintln(out, "no differences")
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		start := p.page() * p.pageSize
+		end := min(start+p.pageSize, len(p.entries))
+		var b strings.Builder
+		for i := start; i < end; i++ {
+			marker := "  "
+			if i == p.current {
+				marker = "> "
+			}
+			fmt.Fprintf(&b, "%s%s\n", marker, p.entries[i])
+		}
+		_, err := fmt.Fprintf(out, "%s-- difference %d of %d, page %d/%d (j: next, k: previous, n: next page, p: previous page, g: first, G: last, q: quit) --\n",
+			b.String(), p.current+1, len(p.entries), p.page()+1, p.pages())
+		if err != nil {
+			return err
+		}
+
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		switch strings.TrimSpace(scanner.Text()) {
+		case "j":
+			p.current = min(p.current+1, len(p.entries)-1)
+		case "k":
+			p.current = max(p.current-1, 0)
+		case "", "n":
+			if p.page() == p.pages()-1 {
+				return nil
+			}
+			p.turn(p.page() + 1)
+		case "p":
+			p.turn(max(0, p.page()-1))
+		case "g":
+			p.current = 0
+		case "G":
+			p.current = len(p.entries) - 1
+		case "q":
+			return nil
+		}
+	}
+}
+
+func init() {
+	reviewCmd.Flags().IntVar(&reviewPageSize, "page-size", reviewPageSize, "Number of differences on each page.")
+	reviewCmd.Flags().BoolVarP(&diffOptions.IgnoreSeqOrder, "unordered", "u", diffOptions.IgnoreSeqOrder, "Ignore the order of items in arrays during comparison.")
+	reviewCmd.Flags().BoolVar(&diffOptions.IgnoreKeyCase, "ignore-key-case", diffOptions.IgnoreKeyCase, "Match the keys of maps regardless of their case.")
+	reviewCmd.Flags().BoolVar(&sortOutput, "sort-output", sortOutput, "Sort differences by their paths for a deterministic output, such as for golden files.")
+	reviewCmd.Flags().BoolVarP(&formatOptions.Plain, "plain", "p", formatOptions.Plain, "Output without any color formatting.")
+	reviewCmd.Flags().BoolVarP(&formatOptions.Metadata, "metadata", "m", formatOptions.Metadata, "Include additional metadata in the output.")
+	reviewCmd.Flags().BoolVarP(&enableComments, "comment", "c", enableComments, "Include comments in the output when available.")
+	rootCmd.AddCommand(reviewCmd)
+}
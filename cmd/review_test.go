@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPager(t *testing.T) {
+	entries := []string{"+ a: 1", "- b: 2", "~ c: 3 -> 4"}
+	footer := "-- difference %d of 3, page %d/2 (j: next, k: previous, n: next page, p: previous page, g: first, G: last, q: quit) --\n"
+	firstPage := "> + a: 1\n  - b: 2\n" + fmt.Sprintf(footer, 1, 1)
+	lastPage := "> ~ c: 3 -> 4\n" + fmt.Sprintf(footer, 3, 2)
+
+	p := &pager{entries: entries, pageSize: 2}
+	var out bytes.Buffer
+	err := p.run(strings.NewReader("n\np\nG\nq\n"), &out)
+	assert.NoError(t, err)
+	assert.Equal(t, firstPage+lastPage+firstPage+lastPage, out.String())
+
+	t.Run("differences", func(t *testing.T) {
+		p := &pager{entries: entries, pageSize: 2}
+		var out bytes.Buffer
+		err := p.run(strings.NewReader("j\nj\nj\nk\nk\nk\ng\n"), &out)
+		assert.NoError(t, err)
+		secondEntry := "  + a: 1\n> - b: 2\n" + fmt.Sprintf(footer, 2, 1)
+		// Moving past the last or the first difference stays at it, and the page follows the current difference.
+		expected := firstPage + secondEntry + lastPage + lastPage + secondEntry + firstPage + firstPage + firstPage
+		assert.Equal(t, expected, out.String())
+	})
+
+	t.Run("end of input", func(t *testing.T) {
+		p := &pager{entries: entries, pageSize: 2}
+		var out bytes.Buffer
+		err := p.run(strings.NewReader(""), &out)
+		assert.NoError(t, err)
+		assert.Equal(t, firstPage, out.String())
+	})
+
+	t.Run("no differences", func(t *testing.T) {
+		p := &pager{pageSize: 2}
+		var out bytes.Buffer
+		err := p.run(strings.NewReader(""), &out)
+		assert.NoError(t, err)
+		assert.Equal(t, "no differences\n", out.String())
+	})
+}
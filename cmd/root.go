@@ -3,11 +3,14 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"runtime/debug"
 
+	"github.com/fatih/color"
 	"github.com/semihbkgr/yamldiff/compare"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var rootCmd = &cobra.Command{
@@ -18,6 +21,13 @@ var rootCmd = &cobra.Command{
 	DisableFlagsInUseLine: true,
 	RunE:                  run,
 	Version:               version(),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// The formatters color the output unless it is plain, so the output is made plain when it should not be colored,
+		// such as when it is not a terminal or NO_COLOR is set.
+		if color.NoColor {
+			formatOptions.Plain = true
+		}
+	},
 }
 
 func Execute() {
@@ -28,32 +38,311 @@ func Execute() {
 }
 
 var exitOnDifference = false
+var quiet = false
+var recursive = false
+var progress = "never"
+var warnReorder = false
+var sortOutput = false
+var k8sListKeys = false
+var ignoreWhitespaceInValues = false
+var frontMatter = false
+var inline = false
+var maxDepth = -1
+var output = "list"
+var jsonFile = ""
 var enableComments = false
 var diffOptions = compare.DefaultDiffOptions
 var formatOptions = compare.DefaultOutputOptions
 
 func run(cmd *cobra.Command, args []string) error {
-	diffs, err := compare.CompareFile(args[0], args[1], enableComments, diffOptions)
-	if err != nil {
+	if quiet && !exitOnDifference {
+		return errors.New("flag --quiet requires --exit, as the differences are only reported by the exit code")
+	}
+	if err := validateOutput(cmd); err != nil {
 		return err
 	}
+	if recursive && inline {
+		return errors.New("flag --recursive cannot be combined with --inline")
+	}
+	switch progress {
+	case "never", "auto", "always":
+	default:
+		return fmt.Errorf("invalid progress %q: must be one of auto, always, never", progress)
+	}
+	if progress != "never" && !recursive {
+		return errors.New("flag --progress requires --recursive, as the progress is reported per file")
+	}
+	if recursive && output == "json" {
+		return errors.New("flag --recursive cannot be combined with --output json, as the output of each file is printed separately")
+	}
+	if recursive && jsonFile != "" {
+		return errors.New("flag --recursive cannot be combined with --json-file, as the differences of each file are compared separately")
+	}
+
+	switch formatOptions.PathStyle {
+	case compare.PathStyleDot, compare.PathStylePointer, compare.PathStyleKubectl:
+	default:
+		return fmt.Errorf("invalid path style %q: must be one of dot, pointer, kubectl", formatOptions.PathStyle)
+	}
 
-	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", diffs.Format(formatOptions))
+	opts := compareOptions()
 
-	if exitOnDifference && diffs.HasDiff() {
+	var differs bool
+	var err error
+	if recursive {
+		differs, err = compareDirs(cmd, args[0], args[1], opts)
+	} else {
+		differs, err = compareArgs(cmd, args[0], args[1], opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if exitOnDifference && differs {
+		if quiet {
+			// The error is still returned for the exit code, but it is not printed either.
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+		}
 		return errors.New("yaml files have difference(s)")
 	}
 
 	return nil
 }
 
+// compareArgs compares the files, or the inline yaml strings, that are given as the arguments and prints their differences.
+// It reports whether there is any difference.
+func compareArgs(cmd *cobra.Command, leftArg, rightArg string, opts compare.DiffOptions) (bool, error) {
+	left, right, err := readInputs(cmd, leftArg, rightArg)
+	if err != nil {
+		return false, err
+	}
+	return compareInputs(cmd.OutOrStdout(), cmd.ErrOrStderr(), "", leftArg, rightArg, left, right, opts)
+}
+
+// compareInputs compares the contents of the named inputs and prints their differences to out and their warnings to errOut,
+// unless the quiet flag is set.
+// If a header is given, it is printed before the differences, and nothing is printed if there are none.
+// It reports whether there is any difference.
+func compareInputs(out, errOut io.Writer, header, leftName, rightName string, left, right []byte, opts compare.DiffOptions) (bool, error) {
+	var err error
+	if frontMatter {
+		if left, err = compare.ExtractFrontMatter(left); err != nil {
+			return false, fmt.Errorf("%s: %w", leftName, err)
+		}
+		if right, err = compare.ExtractFrontMatter(right); err != nil {
+			return false, fmt.Errorf("%s: %w", rightName, err)
+		}
+	}
+
+	result, err := compare.CompareWithResult(left, right, enableComments, opts)
+	if err != nil {
+		return false, err
+	}
+	diffs := result.Diffs
+	if sortOutput {
+		diffs.SortByPath()
+	}
+
+	if jsonFile != "" {
+		if err := writeJSONFile(jsonFile, diffs); err != nil {
+			return false, err
+		}
+	}
+
+	differs := diffs.HasDiff()
+	if quiet || (header != "" && !differs) {
+		return differs, nil
+	}
+	if header != "" {
+		fmt.Fprintf(out, "# %s\n", header)
+	}
+	if err := printDiffs(out, errOut, leftName, rightName, left, right, diffs, result); err != nil {
+		return false, err
+	}
+	return differs, nil
+}
+
+// writeJSONFile writes the differences to the named file in the json output format, whatever the output format is.
+func writeJSONFile(name string, diffs compare.FileDiffs) error {
+	b, err := diffs.FormatJSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(b, '\n'), 0o644)
+}
+
+// printDiffs prints the differences in the requested output format, followed by the warnings of the comparison.
+// The annotations of the github output refer to the named left file for the deletions and to the right one otherwise.
+func printDiffs(out, errOut io.Writer, leftName, rightName string, left, right []byte, diffs compare.FileDiffs, result *compare.CompareResult) error {
+	switch output {
+	case "full":
+		fmt.Fprintf(out, "%s\n", compare.FormatFull(left, right, diffs, formatOptions))
+	case "unified":
+		fmt.Fprintf(out, "%s\n", compare.FormatUnified(left, right, diffs, formatOptions))
+	case "fields":
+		fmt.Fprintf(out, "%s\n", diffs.FormatFields())
+	case "github":
+		fmt.Fprintf(out, "%s\n", compare.FormatGitHub(leftName, rightName, diffs))
+	case "json":
+		b, err := diffs.FormatJSON()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s\n", b)
+	default:
+		if _, err := diffs.FormatTo(out, formatOptions); err != nil {
+			return err
+		}
+		fmt.Fprintln(out)
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(errOut, "warning: %s\n", warning)
+	}
+	if warnReorder && result.ReorderedSequences > 0 {
+		fmt.Fprintf(errOut, "warning: %d sequence(s) reordered\n", result.ReorderedSequences)
+	}
+	return nil
+}
+
+// listOnlyFlags are the flags that only apply to the list output, as the other outputs do not render the differences one by one.
+var listOnlyFlags = []string{
+	"paths-only", "metadata", "extended-metadata", "collection-sizes", "group", "preserve-quotes", "empty-placeholder", "ascii", "max-value-lines",
+	"path-style", "counts", "counts-only", "grand-total", "changed-docs-only", "collapse-depth",
+}
+
+// validateOutput checks that the output is known and that it is not combined with the flags that do not apply to it.
+func validateOutput(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("context") && output != "unified" {
+		return fmt.Errorf("flag --context cannot be combined with --output %s", output)
+	}
+	if diffOptions.Canonicalize && (output == "full" || output == "unified" || output == "github") {
+		// These outputs mark the lines of the files, while the lines of the differences refer to the canonical documents.
+		return fmt.Errorf("flag --canonicalize cannot be combined with --output %s", output)
+	}
+	switch output {
+	case "list":
+		return nil
+	case "full", "unified", "fields", "json", "github":
+	default:
+		return fmt.Errorf("invalid output %q: must be one of list, full, unified, fields, json, github", output)
+	}
+	for _, name := range listOnlyFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("flag --%s cannot be combined with --output %s", name, output)
+		}
+	}
+	return nil
+}
+
+// readInputs returns the contents of the given files, or the arguments themselves when they are inline yaml strings.
+// A file given as "-" is read from the standard input, which can be used for only one of them.
+func readInputs(cmd *cobra.Command, leftArg, rightArg string) ([]byte, []byte, error) {
+	if inline {
+		return []byte(leftArg), []byte(rightArg), nil
+	}
+	if leftArg == "-" && rightArg == "-" {
+		return nil, nil, errors.New("only one of the files can be read from stdin")
+	}
+	left, err := readInput(cmd, leftArg)
+	if err != nil {
+		return nil, nil, err
+	}
+	right, err := readInput(cmd, rightArg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return left, right, nil
+}
+
+func readInput(cmd *cobra.Command, name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(cmd.InOrStdin())
+	}
+	return os.ReadFile(name)
+}
+
+// compareOptions returns the diff options with the presets and normalizations that are enabled by the flags.
+func compareOptions() compare.DiffOptions {
+	opts := diffOptions
+	if k8sListKeys {
+		opts.MatchByKeys = compare.K8sListKeys
+	}
+	opts.Normalizers = append([]compare.ScalarNormalizer(nil), diffOptions.Normalizers...)
+	if ignoreWhitespaceInValues {
+		opts.Normalizers = append(opts.Normalizers, compare.TrimNormalizer)
+	}
+	if maxDepth >= 0 {
+		depth := maxDepth
+		opts.MaxDepth = &depth
+	}
+	return opts
+}
+
 func init() {
 	rootCmd.Flags().BoolVarP(&exitOnDifference, "exit", "e", false, "Exit with a non-zero status code if differences are found between yaml files.")
+	rootCmd.Flags().BoolVarP(&recursive, "recursive", "r", recursive, "Take the arguments as directories and compare the yaml files with the same relative paths in them.")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", quiet, "Output nothing and report the differences only by the exit code (requires the exit flag).")
 	rootCmd.Flags().BoolVarP(&diffOptions.IgnoreSeqOrder, "unordered", "u", diffOptions.IgnoreSeqOrder, "Ignore the order of items in arrays during comparison.")
+	rootCmd.Flags().BoolVar(&diffOptions.SequenceLCS, "sequence-lcs", diffOptions.SequenceLCS, "Align the items of arrays by their longest common subsequence, so an inserted or deleted item does not affect the items after it.")
+	rootCmd.Flags().BoolVar(&diffOptions.DetectMoves, "detect-moves", diffOptions.DetectMoves, "Report the items of arrays that are matched regardless of their order but move to another position (applicable with the unordered flag or key matching).")
+	rootCmd.Flags().BoolVar(&diffOptions.DetectRenames, "detect-renames", diffOptions.DetectRenames, "Report a deleted key and an added key of the same map with equal values as a renamed key.")
+	rootCmd.Flags().BoolVar(&diffOptions.DetectKeyOrder, "detect-key-order", diffOptions.DetectKeyOrder, "Report the maps that have the same keys and values in a different order.")
+	rootCmd.Flags().BoolVarP(&diffOptions.IntersectionOnly, "intersection", "i", diffOptions.IntersectionOnly, "Compare only the keys that exist in both yaml files.")
+	rootCmd.Flags().BoolVar(&diffOptions.TreatNullAsAbsent, "null-as-absent", diffOptions.TreatNullAsAbsent, "Consider a key with a null value equal to the key being missing in the other yaml file.")
+	rootCmd.Flags().StringArrayVar(&diffOptions.IncludePaths, "include", diffOptions.IncludePaths, "Output only the differences at or under the paths matching the pattern, such as 'spec' or 'spec.containers[*].image' (repeatable).")
+	rootCmd.Flags().StringArrayVar(&diffOptions.ExcludePaths, "exclude", diffOptions.ExcludePaths, "Omit the differences at or under the paths matching the pattern, such as 'metadata.annotations', even when they are included (repeatable).")
+	rootCmd.Flags().IntVar(&maxDepth, "max-depth", maxDepth, "Collapse the differences within a map or an array at the given depth into a single modification, where 0 is the document itself (unlimited when negative).")
+	rootCmd.Flags().BoolVar(&diffOptions.IgnoreKeyCase, "ignore-key-case", diffOptions.IgnoreKeyCase, "Match the keys of maps regardless of their case.")
+	rootCmd.Flags().BoolVar(&diffOptions.NormalizeNewlines, "normalize-newlines", diffOptions.NormalizeNewlines, "Consider the CRLF line endings within block scalars equal to LF line endings.")
+	rootCmd.Flags().BoolVar(&diffOptions.IgnoreValueCase, "ignore-value-case", diffOptions.IgnoreValueCase, "Compare string values regardless of their case.")
+	rootCmd.Flags().BoolVar(&diffOptions.ResolveMergeKeys, "resolve-merge-keys", diffOptions.ResolveMergeKeys, "Compare the effective keys of maps that use merge keys such as '<<: *defaults' instead of the '<<' key itself.")
+	rootCmd.Flags().BoolVar(&diffOptions.ExpandAliases, "expand-aliases", diffOptions.ExpandAliases, "Compare aliases such as '*defaults' as the values of the anchors they refer to.")
+	rootCmd.Flags().BoolVar(&diffOptions.SharedAnchors, "shared-anchors", diffOptions.SharedAnchors, "Make the anchors of the first document available to the aliases and merge keys of the later documents, such as a document of defaults (applicable with --expand-aliases or --resolve-merge-keys).")
+	rootCmd.Flags().BoolVar(&diffOptions.StrictTypes, "strict-types", diffOptions.StrictTypes, "Fail as soon as a value changes its type, such as a map that becomes a string.")
+	rootCmd.Flags().BoolVar(&diffOptions.Canonicalize, "canonicalize", diffOptions.Canonicalize, "Re-serialize the yaml files in a canonical form before comparison, resolving anchors and merge keys (slower, not applicable with the full, unified and github outputs).")
+	rootCmd.Flags().BoolVar(&diffOptions.CompareComments, "compare-comments", diffOptions.CompareComments, "Report the values whose comments differ, even though the values themselves are equal.")
+	rootCmd.Flags().BoolVar(&ignoreWhitespaceInValues, "ignore-whitespace-in-values", ignoreWhitespaceInValues, "Ignore the leading and trailing whitespace of values during comparison.")
+	rootCmd.Flags().BoolVar(&inline, "inline", inline, "Take the arguments as yaml strings instead of file paths, such as 'a: 1' 'a: 2'.")
+	rootCmd.Flags().BoolVar(&frontMatter, "front-matter", frontMatter, "Compare only the yaml front matter of the files, such as markdown files, which is enclosed by '---' lines at the beginning.")
+	rootCmd.Flags().BoolVar(&k8sListKeys, "k8s-list-keys", k8sListKeys, "Match the items of Kubernetes lists, such as containers and env, by their name or other identifying key instead of their position.")
+	rootCmd.Flags().StringVar(&progress, "progress", progress, "Print the progress of the files compared with --recursive to stderr, such as '[12/340] comparing app.yaml', either 'auto' when stderr is a terminal, which --progress alone means, 'always' or 'never'.")
+	rootCmd.Flags().Lookup("progress").NoOptDefVal = "auto"
+	rootCmd.Flags().IntVar(&diffOptions.Jobs, "jobs", diffOptions.Jobs, "Maximum number of documents, or files with --recursive, compared in parallel, which defaults to GOMAXPROCS when it is 0.")
+	rootCmd.Flags().BoolVar(&warnReorder, "warn-reorder", warnReorder, "Warn about the arrays whose items are reordered (applicable with the unordered flag).")
+	rootCmd.Flags().BoolVar(&sortOutput, "sort-output", sortOutput, "Sort differences by their paths for a deterministic output, such as for golden files.")
+	rootCmd.Flags().StringVarP(&output, "output", "o", output, "Output format, either 'list' of differences, 'full' to print both yaml files side by side with the changed lines marked, 'unified' to print them as a unified diff, 'fields' to list the paths of changed fields as kubectl renders them, 'json', or 'github' to annotate the changed lines of the files in GitHub Actions.")
+	rootCmd.Flags().StringVar(&jsonFile, "json-file", jsonFile, "Write the differences in the json output format to the given file as well, such as for CI artifacts, while printing them in the requested output format.")
+	rootCmd.Flags().IntVar(&formatOptions.Context, "context", formatOptions.Context, "Number of unchanged lines to output around the changed ones, leaving out the others (applicable with the unified output).")
 	rootCmd.Flags().BoolVarP(&formatOptions.Plain, "plain", "p", formatOptions.Plain, "Output without any color formatting.")
-	rootCmd.Flags().BoolVarP(&formatOptions.Silent, "silent", "s", formatOptions.Silent, "Suppress output of values, showing only differences.")
-	rootCmd.Flags().BoolVarP(&formatOptions.Metadata, "metadata", "m", formatOptions.Metadata, "Include additional metadata in the output (not applicable with the silent flag).")
+	rootCmd.Flags().BoolVarP(&formatOptions.PathsOnly, "paths-only", "s", formatOptions.PathsOnly, "Output only the paths of differences, without their values (aliases: --silent, --no-values).")
+	rootCmd.Flags().BoolVarP(&formatOptions.Metadata, "metadata", "m", formatOptions.Metadata, "Include additional metadata in the output (not applicable with the paths-only flag).")
+	rootCmd.Flags().BoolVar(&formatOptions.ExtendedMetadata, "extended-metadata", formatOptions.ExtendedMetadata, "Include the number of leaves and the depth of added and deleted collections in the metadata (applicable with the metadata flag).")
+	rootCmd.Flags().BoolVar(&formatOptions.CollectionSizes, "collection-sizes", formatOptions.CollectionSizes, "Include the number of items or keys of added and deleted arrays and maps in the output.")
+	rootCmd.Flags().BoolVarP(&formatOptions.GroupByType, "group", "g", formatOptions.GroupByType, "Group differences by their type as added, deleted and modified.")
+	rootCmd.Flags().BoolVar(&formatOptions.PreserveQuotes, "preserve-quotes", formatOptions.PreserveQuotes, "Output string values with their original quoting style.")
+	rootCmd.Flags().StringVar(&formatOptions.EmptyPlaceholder, "empty-placeholder", formatOptions.EmptyPlaceholder, "Text to output in place of null and empty values, such as '<empty>'.")
+	rootCmd.Flags().BoolVar(&formatOptions.ASCIISymbols, "ascii", formatOptions.ASCIISymbols, "Render moved items and renamed keys with the ASCII signs '^' and '=' and the arrow '->'.")
+	rootCmd.Flags().IntVar(&formatOptions.MaxValueLines, "max-value-lines", formatOptions.MaxValueLines, "Truncate the values that span more lines, such as maps and block scalars, to the given number of lines (unlimited when 0).")
+	rootCmd.Flags().StringVar((*string)(&formatOptions.PathStyle), "path-style", string(formatOptions.PathStyle), "Notation of the paths in the output, either 'dot' such as a.b[0], 'pointer' such as /a/b/0 or 'kubectl' such as .a.b[0].")
+	rootCmd.Flags().BoolVar(&formatOptions.IncludeCounts, "counts", formatOptions.IncludeCounts, "Output the number of added, deleted and modified differences for each document.")
+	rootCmd.Flags().BoolVar(&formatOptions.CountsOnly, "counts-only", formatOptions.CountsOnly, "Output only the number of added, deleted and modified differences for each document, without the differences.")
+	rootCmd.Flags().BoolVar(&formatOptions.GrandTotal, "grand-total", formatOptions.GrandTotal, "Output the number of differences across all documents at the end.")
+	rootCmd.Flags().BoolVar(&formatOptions.ChangedDocsOnly, "changed-docs-only", formatOptions.ChangedDocsOnly, "Omit the documents without differences from the output, even when their counts are requested.")
+	rootCmd.Flags().IntVar(&formatOptions.CollapseDepth, "collapse-depth", formatOptions.CollapseDepth, "Output only the first of the differences whose paths share the same prefix up to the given depth, noting the number of the others.")
 	rootCmd.Flags().BoolVarP(&enableComments, "comment", "c", enableComments, "Include comments in the output when available.")
+	rootCmd.Flags().SetNormalizeFunc(flagAliases)
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+}
+
+// flagAliases maps the former and alternative names of flags onto their canonical names.
+func flagAliases(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	switch name {
+	case "silent", "no-values":
+		name = "paths-only"
+	}
+	return pflag.NormalizedName(name)
 }
 
 // buildVersion is set by ldflags
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// execute runs the root command with the given arguments and returns its output,
+// after resetting the flags that are set by the previous runs.
+func execute(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	resetFlags := func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			// Setting a slice flag appends to its value, so it is replaced instead.
+			assert.NoError(t, v.Replace(nil))
+		} else {
+			assert.NoError(t, f.Value.Set(f.DefValue))
+		}
+		f.Changed = false
+	}
+	rootCmd.Flags().VisitAll(resetFlags)
+	for _, c := range rootCmd.Commands() {
+		c.Flags().VisitAll(resetFlags)
+	}
+
+	rootCmd.SilenceErrors = false
+	rootCmd.SilenceUsage = false
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+// writeFile writes the content into a file in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestIgnoreWhitespaceInValues(t *testing.T) {
+	left := writeFile(t, "left.yaml", "name: \"app  \"\nimage: \"app:v1\"\n")
+	right := writeFile(t, "right.yaml", "name: \"app\"\nimage: \" app:v1\"\n")
+
+	output, err := execute(t, "--plain", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ name: \"app  \" -> \"app\"\n~ image: \"app:v1\" -> \" app:v1\"\n", output)
+
+	output, err = execute(t, "--plain", "--ignore-whitespace-in-values", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, "\n", output)
+}
+
+func TestFrontMatter(t *testing.T) {
+	left := writeFile(t, "left.md", "---\ntitle: Intro\ndraft: true\n---\n# Intro\n")
+	right := writeFile(t, "right.md", "---\ntitle: Introduction\ndraft: true\n---\n# Introduction\n\nMore content.\n")
+
+	output, err := execute(t, "--plain", "--front-matter", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ title: Intro -> Introduction\n", output)
+
+	body := writeFile(t, "body.md", "# Intro\n")
+	_, err = execute(t, "--plain", "--front-matter", left, body)
+	assert.EqualError(t, err, body+": no front matter found at the beginning of the document")
+}
+
+func TestInline(t *testing.T) {
+	output, err := execute(t, "--plain", "--inline", "a: 1\nb: [x, y]", "a: 2\nb: [x, z]")
+	assert.NoError(t, err)
+	assert.Equal(t, "~ a: 1 -> 2\n~ b[1]: y -> z\n", output)
+
+	output, err = execute(t, "--plain", "--inline", "a: 1", "a: 1")
+	assert.NoError(t, err)
+	assert.Equal(t, "\n", output)
+
+	_, err = execute(t, "--plain", "a: 1", "a: 2")
+	assert.Error(t, err)
+}
+
+func TestValidateOutput(t *testing.T) {
+	valid := [][]string{
+		{"--output", "list", "--paths-only", "--counts"},
+		{"--output", "full", "--plain"},
+		{"--output", "fields", "--unordered"},
+		{"--output", "json", "--unordered"},
+		{"--output", "unified", "--plain"},
+		{"--output", "unified", "--context", "3"},
+		{"--metadata", "--group"},
+		{"--output", "json", "--canonicalize"},
+	}
+	for _, args := range valid {
+		_, err := execute(t, append(args, "--inline", "a: 1", "a: 2")...)
+		assert.NoError(t, err, args)
+	}
+
+	invalid := map[string][]string{
+		`invalid output "yaml": must be one of list, full, unified, fields, json, github`: {"--output", "yaml"},
+		"flag --context cannot be combined with --output list":                            {"--context", "3"},
+		"flag --metadata cannot be combined with --output json":                           {"--output", "json", "--metadata"},
+		"flag --paths-only cannot be combined with --output fields":                       {"--output", "fields", "--paths-only"},
+		"flag --paths-only cannot be combined with --output full":                         {"-o", "full", "--silent"},
+		"flag --path-style cannot be combined with --output full":                         {"-o", "full", "--path-style", "dot"},
+		"flag --counts cannot be combined with --output fields":                           {"--counts", "-o", "fields"},
+		"flag --canonicalize cannot be combined with --output unified":                    {"--canonicalize", "-o", "unified"},
+		"flag --canonicalize cannot be combined with --output full":                       {"--canonicalize", "-o", "full"},
+		"flag --canonicalize cannot be combined with --output github":                     {"--canonicalize", "-o", "github"},
+	}
+	for message, args := range invalid {
+		_, err := execute(t, append(args, "--inline", "a: 1", "a: 2")...)
+		assert.EqualError(t, err, message, args)
+	}
+}
+
+func TestIncludeExclude(t *testing.T) {
+	left := "metadata:\n  name: app\n  annotations:\n    revision: 1\nspec:\n  replicas: 1\n"
+	right := "metadata:\n  name: web\n  annotations:\n    revision: 2\nspec:\n  replicas: 2\n"
+
+	output, err := execute(t, "--plain", "--inline", "--include", "spec", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ spec.replicas: 1 -> 2\n", output)
+
+	output, err = execute(t, "--plain", "--inline", "--include", "spec", "--include", "metadata", "--exclude", "metadata.annotations", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ metadata.name: app -> web\n~ spec.replicas: 1 -> 2\n", output)
+}
+
+func TestOutput(t *testing.T) {
+	output, err := execute(t, "--plain", "--inline", "-o", "unified", "a: 1\nb: 2\n", "a: 1\nb: 3\n")
+	assert.NoError(t, err)
+	assert.Equal(t, " a: 1\n-b: 2\n+b: 3\n", output)
+
+	output, err = execute(t, "--inline", "-o", "json", "a: 1\nb: 2\n", "a: 1\nb: 3\n")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[[{"type": "modified", "path": "b", "line": 2, "nodeType": "Integer", "oldValue": "2", "newValue": "3"}]]`, output)
+
+	leftFile := writeFile(t, "left.yaml", "a: 1\nb: 2\nc: 3\n")
+	rightFile := writeFile(t, "right.yaml", "b: 3\na: 1\n")
+	output, err = execute(t, "-o", "github", leftFile, rightFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "::warning file="+rightFile+",line=1::~ b: 2 -> 3\n::warning file="+leftFile+",line=3::- c: 3\n", output)
+}
+
+func TestJSONFile(t *testing.T) {
+	jsonFile := filepath.Join(t.TempDir(), "diff.json")
+	output, err := execute(t, "--plain", "--inline", "--json-file", jsonFile, "a: 1\nb: 2\n", "a: 1\nb: 3\nc: 4\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "~ b: 2 -> 3\n+ c: 4\n", output)
+
+	b, err := os.ReadFile(jsonFile)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[[
+		{"type": "modified", "path": "b", "line": 2, "nodeType": "Integer", "oldValue": "2", "newValue": "3"},
+		{"type": "added", "path": "c", "line": 3, "nodeType": "Integer", "value": "4"}
+	]]`, string(b))
+
+	// The json file is written even if the differences are only reported by the exit code.
+	assert.NoError(t, os.Remove(jsonFile))
+	output, err = execute(t, "--quiet", "--exit", "--inline", "--json-file", jsonFile, "a: 1\n", "a: 2\n")
+	assert.EqualError(t, err, "yaml files have difference(s)")
+	assert.Empty(t, output)
+	b, err = os.ReadFile(jsonFile)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[[{"type": "modified", "path": "a", "line": 1, "nodeType": "Integer", "oldValue": "1", "newValue": "2"}]]`, string(b))
+
+	_, err = execute(t, "--recursive", "--json-file", jsonFile, t.TempDir(), t.TempDir())
+	assert.EqualError(t, err, "flag --recursive cannot be combined with --json-file, as the differences of each file are compared separately")
+}
+
+func TestSortOutput(t *testing.T) {
+	left := "z: 1\nitems: [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10]\na: 1\n"
+	right := "z: 2\nitems: [0, 1, x, 3, 4, 5, 6, 7, 8, 9, y]\na: 2\n"
+
+	output, err := execute(t, "--plain", "--inline", left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ z: 1 -> 2\n~ items[2]: 2 -> x\n~ items[10]: 10 -> y\n~ a: 1 -> 2\n", output)
+
+	// The paths are sorted with the indexes compared as numbers, and the output is the same on every run.
+	expected := "~ a: 1 -> 2\n~ items[2]: 2 -> x\n~ items[10]: 10 -> y\n~ z: 1 -> 2\n"
+	for i := 0; i < 5; i++ {
+		output, err = execute(t, "--plain", "--inline", "--sort-output", left, right)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, output)
+	}
+}
+
+func TestQuiet(t *testing.T) {
+	output, err := execute(t, "--quiet", "--exit", "--inline", "a: 1", "a: 2")
+	assert.EqualError(t, err, "yaml files have difference(s)")
+	assert.Empty(t, output)
+
+	output, err = execute(t, "--quiet", "--exit", "--inline", "a: 1", "a: 1")
+	assert.NoError(t, err)
+	assert.Empty(t, output)
+
+	_, err = execute(t, "--quiet", "--inline", "a: 1", "a: 2")
+	assert.EqualError(t, err, "flag --quiet requires --exit, as the differences are only reported by the exit code")
+}
+
+func TestStdin(t *testing.T) {
+	file := writeFile(t, "right.yaml", "a: 1\nb: 3\n")
+
+	rootCmd.SetIn(bytes.NewBufferString("a: 1\nb: 2\n"))
+	defer rootCmd.SetIn(nil)
+	output, err := execute(t, "--plain", "-", file)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ b: 2 -> 3\n", output)
+
+	_, err = execute(t, "--plain", "-", "-")
+	assert.EqualError(t, err, "only one of the files can be read from stdin")
+}
@@ -2,12 +2,61 @@ package compare
 
 import (
 	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/token"
 )
 
-func compareNodes(leftNode, rightNode ast.Node, opts DiffOptions) []*Diff {
+// comparator holds the options and the state of a single comparison.
+type comparator struct {
+	opts DiffOptions
+
+	// reorderedSequences counts the sequences whose items are reordered,
+	// which is only tracked when the items are matched regardless of their order, as with IgnoreSeqOrder or MatchByKeys.
+	reorderedSequences int
+
+	// err is the first error that is encountered, which stops the comparison.
+	err error
+
+	// probing is set when the comparator tries a candidate node pair, in which case an error that depends on the pair,
+	// like a type mismatch with StrictTypes, is not recorded, as the pair is just rejected.
+	probing bool
+
+	// warnings are the diagnostics that do not stop the comparison, in the order they are encountered and without duplicates.
+	warnings []string
+
+	// leftAnchors and rightAnchors are the anchors of each side that the merge keys and aliases refer to,
+	// which are only collected when ResolveMergeKeys or ExpandAliases is set.
+	leftAnchors  anchors
+	rightAnchors anchors
+}
+
+func newComparator(opts DiffOptions) *comparator {
+	return &comparator{opts: opts}
+}
+
+// fail records the error to stop the comparison, unless an error is already recorded.
+func (c *comparator) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *comparator) compareNodes(leftNode, rightNode ast.Node) []*Diff {
+	if c.err != nil {
+		return nil
+	}
+
+	// Empty documents have no body, and they are equal to each other.
+	if leftNode == nil && rightNode == nil {
+		return nil
+	}
+
 	if leftNode == nil {
 		return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
 	}
@@ -16,32 +65,77 @@ func compareNodes(leftNode, rightNode ast.Node, opts DiffOptions) []*Diff {
 		return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
 	}
 
-	// When the map's key size is one, it is just represented by MappingValueNode instead of MappingNode in AST.
-	// Wrap MappingValueNode by MappingNode if needed.
-	if leftNode.Type() == ast.MappingValueType {
-		path := leftNode.GetPath()
-		leftNode = ast.Mapping(leftNode.GetToken(), false, leftNode.(*ast.MappingValueNode))
-		leftNode.SetPath(path)
+	leftExpanded := c.expandAlias(leftNode, c.leftAnchors)
+	rightExpanded := c.expandAlias(rightNode, c.rightAnchors)
+	leftResolved := wrapMappingValueNode(c.unwrapAnchor(leftExpanded))
+	rightResolved := wrapMappingValueNode(c.unwrapAnchor(rightExpanded))
+
+	diffs := c.compareResolvedNodes(leftResolved, rightResolved)
+	// The differences under an expanded alias are reported under the path of the alias rather than of its anchor.
+	if leftExpanded != leftNode {
+		relabelPaths(diffs, true, GetNodePath(leftResolved), GetNodePath(leftNode))
+	}
+	if rightExpanded != rightNode {
+		relabelPaths(diffs, false, GetNodePath(rightResolved), GetNodePath(rightNode))
 	}
-	if rightNode.Type() == ast.MappingValueType {
-		path := rightNode.GetPath()
-		rightNode = ast.Mapping(rightNode.GetToken(), false, rightNode.(*ast.MappingValueNode))
-		rightNode.SetPath(path)
+	return diffs
+}
+
+// compareResolvedNodes compares the nodes whose aliases are expanded and anchors are unwrapped.
+func (c *comparator) compareResolvedNodes(leftNode, rightNode ast.Node) []*Diff {
+	if c.identicalCollections(leftNode, rightNode) {
+		// The children are not compared, but their duplicate keys are reported all the same.
+		c.warnNestedDuplicateKeys(leftNode)
+		c.warnNestedDuplicateKeys(rightNode)
+		return nil
+	}
+
+	if c.opts.CoerceStringNumbers && (numericStringEqual(leftNode, rightNode) || numericStringEqual(rightNode, leftNode)) {
+		return nil
+	}
+
+	if c.opts.CoerceScalarTypes && (scalarStringEqual(leftNode, rightNode) || scalarStringEqual(rightNode, leftNode)) {
+		return nil
+	}
+
+	if c.normalizedEqual(leftNode, rightNode) {
+		return nil
 	}
 
 	if leftNode.Type() != rightNode.Type() {
+		if c.opts.StrictTypes && !c.probing && !(isStringNode(leftNode) && isStringNode(rightNode)) {
+			c.fail(fmt.Errorf("incompatible types at %s: %s and %s", GetNodePath(leftNode), leftNode.Type(), rightNode.Type()))
+			return nil
+		}
 		return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
 	}
 
 	switch leftNode.Type() {
 	case ast.MappingType:
-		return compareMappingNodes(leftNode.(*ast.MappingNode), rightNode.(*ast.MappingNode), opts)
+		diffs := c.compareMappingNodes(leftNode.(*ast.MappingNode), rightNode.(*ast.MappingNode))
+		if len(diffs) > 0 && c.atMaxDepth(leftNode) {
+			return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
+		}
+		return append(c.compareComments(leftNode, rightNode), diffs...)
 	case ast.SequenceType:
-		return compareSequenceNodes(leftNode.(*ast.SequenceNode), rightNode.(*ast.SequenceNode), opts)
+		diffs := c.compareSequenceNodes(leftNode.(*ast.SequenceNode), rightNode.(*ast.SequenceNode))
+		for _, diff := range diffs {
+			diff.sequence = rightNode
+		}
+		if len(diffs) > 0 && c.atMaxDepth(leftNode) {
+			return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
+		}
+		return append(c.compareComments(leftNode, rightNode), diffs...)
 	case ast.StringType:
 		leftStringNode := leftNode.(*ast.StringNode)
 		rightStringNode := rightNode.(*ast.StringNode)
-		if leftStringNode.Value != rightStringNode.Value {
+		if !c.stringsEqual(leftStringNode.Value, rightStringNode.Value) {
+			return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
+		}
+	case ast.LiteralType:
+		leftLiteralNode := leftNode.(*ast.LiteralNode)
+		rightLiteralNode := rightNode.(*ast.LiteralNode)
+		if !c.literalsEqual(leftLiteralNode, rightLiteralNode) {
 			return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
 		}
 	case ast.IntegerType:
@@ -63,66 +157,508 @@ func compareNodes(leftNode, rightNode ast.Node, opts DiffOptions) []*Diff {
 			return []*Diff{{leftNode: leftNode, rightNode: rightNode}}
 		}
 	}
-	return nil
+	return c.compareComments(leftNode, rightNode)
+}
+
+// atMaxDepth reports whether the collection is at MaxDepth, where the differences within it are collapsed
+// into a single modification of the collection itself. The depth of a path is the number of its keys and indices.
+func (c *comparator) atMaxDepth(n ast.Node) bool {
+	return c.opts.MaxDepth != nil && len(splitPath(GetNodePath(n))) == *c.opts.MaxDepth
 }
 
-func compareMappingNodes(leftNode, rightNode *ast.MappingNode, opts DiffOptions) []*Diff {
-	leftKeyValueMap := mappingValueNodesIntoMap(leftNode)
-	rightKeyValueMap := mappingValueNodesIntoMap(rightNode)
-	keyDiffsMap := make(map[string][]*Diff)
-	for k, leftValue := range leftKeyValueMap {
+// stringsEqual reports whether the string values are equal, regardless of their surrounding white space
+// if IgnoreScalarWhitespace is set, and regardless of their case if IgnoreValueCase is set.
+func (c *comparator) stringsEqual(left, right string) bool {
+	if c.opts.IgnoreScalarWhitespace {
+		left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+	}
+	return left == right || (c.opts.IgnoreValueCase && strings.EqualFold(left, right))
+}
+
+// literalsEqual reports whether the values of the block scalars are equal,
+// regardless of the white space that surrounds the whole values if IgnoreScalarWhitespace is set.
+func (c *comparator) literalsEqual(leftNode, rightNode *ast.LiteralNode) bool {
+	left, right := literalValue(leftNode, c.opts), literalValue(rightNode, c.opts)
+	if c.opts.IgnoreScalarWhitespace {
+		left, right = strings.TrimSpace(left), strings.TrimSpace(right)
+	}
+	return left == right
+}
+
+// literalValue returns the value of the block scalar, where the CRLF line endings are replaced by LF if NormalizeNewlines is set.
+func literalValue(n *ast.LiteralNode, opts DiffOptions) string {
+	value := n.Value.Value
+	if !opts.NormalizeNewlines {
+		return value
+	}
+	// The parser leaves the LF of the CRLF that ends the header, such as "|", at the start of the value.
+	if strings.HasSuffix(n.Start.Origin, "\r") {
+		value = strings.TrimPrefix(value, "\n")
+	}
+	return strings.ReplaceAll(value, "\r\n", "\n")
+}
+
+// identicalCollections reports whether both nodes are collections that are written the same,
+// so they are known to be equal without comparing their children one by one.
+// The collections whose keys or aliases are resolved, or whose keys are checked for conflicts, are always compared one by one.
+func (c *comparator) identicalCollections(leftNode, rightNode ast.Node) bool {
+	if c.opts.IgnoreKeyCase || c.opts.ResolveMergeKeys || c.opts.ExpandAliases {
+		return false
+	}
+	if !isCollectionNode(leftNode) || !isCollectionNode(rightNode) {
+		return false
+	}
+	return identicalTokens(leftNode, rightNode)
+}
+
+// identicalTokens reports whether the tokens of both nodes have the same types and values at the same positions
+// relative to the first token of each node, which is cheaper than rendering the nodes as text.
+// The tokens in between, such as the comments, are compared as well, even if they are not parsed.
+func identicalTokens(leftNode, rightNode ast.Node) bool {
+	leftFirst, leftLast := tokenRange(leftNode)
+	rightFirst, rightLast := tokenRange(rightNode)
+	if leftFirst == nil || rightFirst == nil {
+		return false
+	}
+	for l, r := leftFirst, rightFirst; l != nil && r != nil; l, r = l.Next, r.Next {
+		if l.Type != r.Type || l.Value != r.Value ||
+			l.Position.Line-leftFirst.Position.Line != r.Position.Line-rightFirst.Position.Line ||
+			l.Position.Column-leftFirst.Position.Column != r.Position.Column-rightFirst.Position.Column {
+			return false
+		}
+		if l == leftLast || r == rightLast {
+			return l == leftLast && r == rightLast
+		}
+	}
+	return false
+}
+
+// tokenRange returns the first and the last tokens of the nodes under the node, including itself, by their positions.
+func tokenRange(n ast.Node) (*token.Token, *token.Token) {
+	var first, last *token.Token
+	ast.Walk(lineRangeVisitor(func(n ast.Node) {
+		tk := n.GetToken()
+		if tk == nil {
+			return
+		}
+		if first == nil || positionBefore(tk.Position, first.Position) {
+			first = tk
+		}
+		if last == nil || positionBefore(last.Position, tk.Position) {
+			last = tk
+		}
+	}), n)
+	return first, last
+}
+
+func positionBefore(a, b *token.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
+
+// compareComments returns a difference if the comments of the nodes differ and CompareComments is set.
+// It is only called for the nodes whose values are equal, or collections whose children are compared separately.
+func (c *comparator) compareComments(leftNode, rightNode ast.Node) []*Diff {
+	if !c.opts.CompareComments || commentString(leftNode) == commentString(rightNode) {
+		return nil
+	}
+	return []*Diff{{leftNode: leftNode, rightNode: rightNode, comment: true}}
+}
+
+// commentString returns the comment of the node as it is written in the document, or "<none>" if it has no comment.
+func commentString(n ast.Node) string {
+	if comment := n.GetComment(); comment != nil {
+		return comment.String()
+	}
+	return "<none>"
+}
+
+// warn records the warning, unless the same warning is already recorded.
+func (c *comparator) warn(format string, args ...any) {
+	warning := fmt.Sprintf(format, args...)
+	for _, w := range c.warnings {
+		if w == warning {
+			return
+		}
+	}
+	c.warnings = append(c.warnings, warning)
+}
+
+// isStringNode reports whether the node is a string, which is either a flow scalar or a block scalar.
+func isStringNode(n ast.Node) bool {
+	return n.Type() == ast.StringType || n.Type() == ast.LiteralType
+}
+
+// isCollectionNode reports whether the node is a mapping or a sequence.
+func isCollectionNode(n ast.Node) bool {
+	switch n.Type() {
+	case ast.MappingType, ast.MappingValueType, ast.SequenceType:
+		return true
+	}
+	return false
+}
+
+// probe compares the given nodes without affecting the state of the comparator, so it can be used to try candidate node pairs.
+// It returns the comparator that is used for probing, whose state can be merged via merge when the pair is accepted.
+// An error and the warnings are recorded in any case, as they are caused by one of the nodes regardless of the pair.
+func (c *comparator) probe(leftNode, rightNode ast.Node) (*comparator, bool) {
+	p := newComparator(c.opts)
+	p.probing = true
+	p.leftAnchors = c.leftAnchors
+	p.rightAnchors = c.rightAnchors
+	diffs := p.compareNodes(leftNode, rightNode)
+	for _, w := range p.warnings {
+		c.warn("%s", w)
+	}
+	if p.err != nil {
+		c.fail(p.err)
+		return p, false
+	}
+	return p, len(diffs) == 0
+}
+
+// merge adds the state collected by the other comparator into this one.
+func (c *comparator) merge(other *comparator) {
+	c.reorderedSequences += other.reorderedSequences
+	if other.err != nil {
+		c.fail(other.err)
+	}
+	for _, w := range other.warnings {
+		c.warn("%s", w)
+	}
+}
+
+// wrapMappingValueNode wraps the MappingValueNode by MappingNode.
+// When the map's key size is one, it is just represented by MappingValueNode instead of MappingNode in AST.
+func wrapMappingValueNode(n ast.Node) ast.Node {
+	if n.Type() != ast.MappingValueType {
+		return n
+	}
+	path := n.GetPath()
+	mappingNode := ast.Mapping(n.GetToken(), false, n.(*ast.MappingValueNode))
+	mappingNode.SetPath(path)
+	return mappingNode
+}
+
+// documents returns the documents of the file. The parser nests the document that follows an empty one, like in "---\n---\na: 1",
+// into the body of the empty document, so such documents are flattened into an empty document with a nil body followed by the nested one.
+func documents(f *ast.File) []*ast.DocumentNode {
+	docs := make([]*ast.DocumentNode, 0, len(f.Docs))
+	for _, doc := range f.Docs {
+		for {
+			nested, ok := doc.Body.(*ast.DocumentNode)
+			if !ok {
+				break
+			}
+			docs = append(docs, &ast.DocumentNode{BaseNode: doc.BaseNode, Start: doc.Start, End: doc.End})
+			doc = nested
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// documentBody returns the body of the document, where a mapping with a single key is wrapped by MappingNode at the root path,
+// as the path of its MappingValueNode is the path of its key.
+func documentBody(doc *ast.DocumentNode) ast.Node {
+	if doc.Body == nil || doc.Body.Type() != ast.MappingValueType {
+		return doc.Body
+	}
+	body := wrapMappingValueNode(doc.Body)
+	body.SetPath("$")
+	return body
+}
+
+// numericLiteralPattern matches plain decimal number literals.
+// Leading zeros, explicit plus signs and non-decimal notations are rejected on purpose,
+// as strings like "08" or "0x1F" are usually identifiers rather than numbers.
+var numericLiteralPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// numericStringEqual reports whether the string node holds a clean numeric literal
+// that is equal to the value of the number node.
+func numericStringEqual(stringNode, numberNode ast.Node) bool {
+	s, ok := stringNode.(*ast.StringNode)
+	if !ok || !numericLiteralPattern.MatchString(s.Value) {
+		return false
+	}
+	switch n := numberNode.(type) {
+	case *ast.IntegerNode:
+		return s.Value == fmt.Sprint(n.Value)
+	case *ast.FloatNode:
+		f, err := strconv.ParseFloat(s.Value, 64)
+		return err == nil && f == n.Value
+	}
+	return false
+}
+
+// scalarStringEqual reports whether the string node holds the canonical representation of the value of the scalar node,
+// such as "42" for 42, "true" for true and "null" for null. The canonical representation of a float is the one that
+// the yaml encoder writes, such as "1.0" for 1.00 and "0.5" for .5, so "1e0" or "08" does not equal the float 1 or 8.
+func scalarStringEqual(stringNode, scalarNode ast.Node) bool {
+	s, ok := stringNode.(*ast.StringNode)
+	if !ok {
+		return false
+	}
+	switch n := scalarNode.(type) {
+	case *ast.IntegerNode:
+		return s.Value == fmt.Sprint(n.Value)
+	case *ast.FloatNode:
+		return s.Value == canonicalFloat(n.Value)
+	case *ast.BoolNode:
+		return s.Value == strconv.FormatBool(n.Value)
+	case *ast.NullNode:
+		return s.Value == "null"
+	}
+	return false
+}
+
+// canonicalFloat returns the float as the yaml encoder writes it, which is its shortest representation,
+// followed by ".0" if it would otherwise read as an integer.
+func canonicalFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (c *comparator) compareMappingNodes(leftNode, rightNode *ast.MappingNode) []*Diff {
+	if c.opts.IgnoreKeyCase {
+		if err := keyCaseConflict(leftNode); err != nil {
+			c.fail(err)
+			return nil
+		}
+		if err := keyCaseConflict(rightNode); err != nil {
+			c.fail(err)
+			return nil
+		}
+	}
+
+	c.warnDuplicateKeys(leftNode)
+	c.warnDuplicateKeys(rightNode)
+
+	leftValues := c.mappingValues(leftNode, c.leftAnchors)
+	rightValues := c.mappingValues(rightNode, c.rightAnchors)
+	leftKeyValueMap := mappingValueNodesIntoMap(leftValues, c.opts)
+	rightKeyValueMap := mappingValueNodesIntoMap(rightValues, c.opts)
+	// The keys are visited in their source order, the left keys first and then the keys that exist only on the right,
+	// so the differences do not depend on the iteration order of the maps.
+	diffs := make([]*Diff, 0)
+	var deleted, added []*ast.MappingValueNode
+	for _, leftValue := range leftValues {
+		k := mappingKey(leftValue.Key, c.opts)
+		// Only the last value of a duplicate key is compared.
+		if leftKeyValueMap[k] != leftValue {
+			continue
+		}
 		rightValue, ok := rightKeyValueMap[k]
 		if !ok {
-			node := leftValue.Value
-			// wrap the MappingValueNode by MappingNode
-			// todo: extract this logic into a function
-			if node.Type() == ast.MappingValueType {
-				path := node.GetPath()
-				node = ast.Mapping(node.GetToken(), false, node.(*ast.MappingValueNode))
-				node.SetPath(path)
+			if !c.opts.IntersectionOnly && !c.absentNull(leftValue.Value, c.leftAnchors) {
+				deleted = append(deleted, leftValue)
 			}
-			keyDiffsMap[k] = []*Diff{{leftNode: node, rightNode: nil}}
 			continue
 		}
-		keyDiffsMap[k] = compareNodes(leftValue.Value, rightValue.Value, opts)
+		valueDiffs := c.compareNodes(leftValue.Value, rightValue.Value)
+		c.relabelMergedValue(valueDiffs, true, leftNode, leftValue)
+		c.relabelMergedValue(valueDiffs, false, rightNode, rightValue)
+		diffs = append(diffs, valueDiffs...)
 	}
-	for k, rightValue := range rightKeyValueMap {
-		_, ok := keyDiffsMap[k]
-		if ok {
+	for _, rightValue := range rightValues {
+		k := mappingKey(rightValue.Key, c.opts)
+		if rightKeyValueMap[k] != rightValue {
+			continue
+		}
+		if _, ok := leftKeyValueMap[k]; ok || c.opts.IntersectionOnly || c.absentNull(rightValue.Value, c.rightAnchors) {
 			continue
 		}
-		node := rightValue.Value
-		// wrap the MappingValueNode by MappingNode
-		if node.Type() == ast.MappingValueType {
-			path := node.GetPath()
-			node = ast.Mapping(node.GetToken(), false, node.(*ast.MappingValueNode))
-			node.SetPath(path)
+		added = append(added, rightValue)
+	}
+
+	if len(diffs) == 0 && len(deleted) == 0 && len(added) == 0 && c.keysReordered(leftValues, rightValues, leftKeyValueMap, rightKeyValueMap) {
+		return []*Diff{{leftNode: leftNode, rightNode: rightNode, keyOrder: true}}
+	}
+
+	renamed := c.renamedKeys(deleted, added)
+	renamedTo := make(map[*ast.MappingValueNode]bool, len(renamed))
+	for _, leftValue := range deleted {
+		diff := &Diff{leftNode: wrapMappingValueNode(leftValue.Value), rightNode: nil}
+		if rightValue, ok := renamed[leftValue]; ok {
+			renamedTo[rightValue] = true
+			diff = &Diff{leftNode: wrapMappingValueNode(leftValue.Value), rightNode: wrapMappingValueNode(rightValue.Value), renamed: true}
+		}
+		c.relabelMergedValue([]*Diff{diff}, true, leftNode, leftValue)
+		diffs = append(diffs, diff)
+	}
+	for _, rightValue := range added {
+		if !renamedTo[rightValue] {
+			diff := &Diff{leftNode: nil, rightNode: wrapMappingValueNode(rightValue.Value)}
+			c.relabelMergedValue([]*Diff{diff}, false, rightNode, rightValue)
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs
+}
+
+// absentNull reports whether the value of a key that exists on one side only is null,
+// so the key is considered equal to the missing key on the other side if TreatNullAsAbsent is set.
+func (c *comparator) absentNull(n ast.Node, anchors anchors) bool {
+	if !c.opts.TreatNullAsAbsent {
+		return false
+	}
+	_, ok := c.unwrapAnchor(c.expandAlias(n, anchors)).(*ast.NullNode)
+	return ok
+}
+
+// keysReordered reports whether the mappings have the same keys in a different order, if DetectKeyOrder is set.
+func (c *comparator) keysReordered(leftValues, rightValues []*ast.MappingValueNode, leftKeyValueMap, rightKeyValueMap map[string]*ast.MappingValueNode) bool {
+	if !c.opts.DetectKeyOrder || len(leftKeyValueMap) != len(rightKeyValueMap) {
+		return false
+	}
+	for k := range leftKeyValueMap {
+		if _, ok := rightKeyValueMap[k]; !ok {
+			return false
 		}
-		keyDiffsMap[k] = []*Diff{{leftNode: nil, rightNode: node}}
 	}
+	return !slices.Equal(c.keyOrder(leftValues, leftKeyValueMap), c.keyOrder(rightValues, rightKeyValueMap))
+}
 
-	allDiffs := make([]*Diff, 0)
-	for _, diffs := range keyDiffsMap {
-		if diffs != nil {
-			allDiffs = append(allDiffs, diffs...)
+// keyOrder returns the keys of the mapping values in their order.
+// Only the last value of a duplicate key counts, as it is the one that is compared.
+func (c *comparator) keyOrder(values []*ast.MappingValueNode, keyValueMap map[string]*ast.MappingValueNode) []string {
+	keys := make([]string, 0, len(keyValueMap))
+	for _, value := range values {
+		if k := mappingKey(value.Key, c.opts); keyValueMap[k] == value {
+			keys = append(keys, k)
 		}
 	}
+	return keys
+}
+
+// keyOrderString renders the keys of the mapping in their order, such as "(build, test)".
+func keyOrderString(n ast.Node) string {
+	m, ok := n.(*ast.MappingNode)
+	if !ok {
+		return n.String()
+	}
+	keys := make([]string, 0, len(m.Values))
+	for _, value := range m.Values {
+		keys = append(keys, keyString(value.Key))
+	}
+	return "(" + strings.Join(keys, ", ") + ")"
+}
 
-	return allDiffs
+// renamedKeys pairs the keys that exist only on the left side with the first keys that exist only on the right side
+// whose values are equal, if DetectRenames is set.
+func (c *comparator) renamedKeys(deleted, added []*ast.MappingValueNode) map[*ast.MappingValueNode]*ast.MappingValueNode {
+	renamed := make(map[*ast.MappingValueNode]*ast.MappingValueNode)
+	if !c.opts.DetectRenames {
+		return renamed
+	}
+	paired := make([]bool, len(added))
+	for _, leftValue := range deleted {
+		for i, rightValue := range added {
+			if paired[i] {
+				continue
+			}
+			if p, ok := c.probe(leftValue.Value, rightValue.Value); ok {
+				c.merge(p)
+				renamed[leftValue] = rightValue
+				paired[i] = true
+				break
+			}
+		}
+	}
+	return renamed
 }
 
-func mappingValueNodesIntoMap(n *ast.MappingNode) map[string]*ast.MappingValueNode {
+func mappingValueNodesIntoMap(values []*ast.MappingValueNode, opts DiffOptions) map[string]*ast.MappingValueNode {
 	keyValueMap := make(map[string]*ast.MappingValueNode)
-	for _, values := range n.Values {
-		keyValueMap[values.Key.String()] = values
+	for _, values := range values {
+		keyValueMap[mappingKey(values.Key, opts)] = values
 	}
 	return keyValueMap
 }
 
-func compareSequenceNodes(leftNode, rightNode *ast.SequenceNode, opts DiffOptions) []*Diff {
+// mappingKey returns the key that is used to match the values of mappings.
+func mappingKey(key ast.MapKeyNode, opts DiffOptions) string {
+	if opts.IgnoreKeyCase {
+		return strings.ToLower(keyString(key))
+	}
+	return keyString(key)
+}
+
+// keyString returns the value of the key regardless of its quoting, so "a", 'a' and a are the same key,
+// such as the quoted keys of JSON documents and the plain keys of yaml documents.
+func keyString(key ast.MapKeyNode) string {
+	if s, ok := key.(*ast.StringNode); ok {
+		return s.Value
+	}
+	return key.String()
+}
+
+// keyCaseConflict returns an error if the mapping has distinct keys that differ only by case,
+// as it is ambiguous which one of them to match when the case of keys is ignored.
+func keyCaseConflict(n *ast.MappingNode) error {
+	keys := make(map[string]string)
+	for _, values := range n.Values {
+		key := keyString(values.Key)
+		foldedKey := strings.ToLower(key)
+		if other, ok := keys[foldedKey]; ok && other != key {
+			return fmt.Errorf("keys %s and %s at %s differ only by case", other, key, GetNodePath(n))
+		}
+		keys[foldedKey] = key
+	}
+	return nil
+}
+
+// warnDuplicateKeys warns about the keys that appear more than once in the mapping, as only their last values are compared.
+func (c *comparator) warnDuplicateKeys(n *ast.MappingNode) {
+	keys := make(map[string]bool)
+	for _, values := range n.Values {
+		key := mappingKey(values.Key, c.opts)
+		if keys[key] {
+			c.warn("duplicate key %s at %s on line %d, only its last value is compared", values.Key.String(), GetNodePath(n), values.Key.GetToken().Position.Line)
+		}
+		keys[key] = true
+	}
+}
+
+// warnNestedDuplicateKeys warns about the duplicate keys of the mappings under the node, including itself.
+func (c *comparator) warnNestedDuplicateKeys(n ast.Node) {
+	ast.Walk(lineRangeVisitor(func(n ast.Node) {
+		if m, ok := n.(*ast.MappingNode); ok {
+			c.warnDuplicateKeys(m)
+		}
+	}), n)
+}
+
+func (c *comparator) compareSequenceNodes(leftNode, rightNode *ast.SequenceNode) []*Diff {
+	ignored := c.ignoredIndices(leftNode, rightNode)
+	if len(c.opts.MatchByKeys) > 0 || len(c.opts.KeyedSequences) > 0 {
+		leftItems := withoutIgnoredIndices(leftNode.Values, ignored)
+		rightItems := withoutIgnoredIndices(rightNode.Values, ignored)
+		if key, ok := c.sequenceMatchKey(c.sequenceKeys(leftNode, rightNode), leftItems, rightItems); ok {
+			return c.compareKeyedSequenceNodes(leftItems, rightItems, key)
+		}
+	}
+
+	if c.opts.IgnoreSeqOrder {
+		return c.compareUnorderedSequenceNodes(leftNode, rightNode)
+	}
+
+	if c.opts.SequenceLCS {
+		return c.compareAlignedSequenceNodes(withoutIgnoredIndices(leftNode.Values, ignored), withoutIgnoredIndices(rightNode.Values, ignored))
+	}
+
 	diffs := make([]*Diff, 0)
 	l := max(len(leftNode.Values), len(rightNode.Values))
 	for i := 0; i < l; i++ {
+		if ignored[i] {
+			continue
+		}
 		var leftValue, rightValue ast.Node
 		if len(leftNode.Values) > i {
 			leftValue = leftNode.Values[i]
@@ -130,63 +666,462 @@ func compareSequenceNodes(leftNode, rightNode *ast.SequenceNode, opts DiffOption
 		if len(rightNode.Values) > i {
 			rightValue = rightNode.Values[i]
 		}
-		diffs = append(diffs, compareNodes(leftValue, rightValue, opts)...)
+		diffs = append(diffs, c.compareNodes(leftValue, rightValue)...)
 	}
 
-	if opts.IgnoreSeqOrder {
-		diffs = ignoreIndexes(diffs, opts)
+	return diffs
+}
+
+// compareAlignedSequenceNodes compares the sequences by aligning the longest common subsequence of their equal items,
+// so an inserted or deleted item does not cause differences in the items after it. The items between the aligned ones
+// are compared in their order, so a changed item is still reported by the differences within it.
+func (c *comparator) compareAlignedSequenceNodes(leftItems, rightItems []ast.Node) []*Diff {
+	// The items with the same hash are known to be equal, so only the others are probed,
+	// as the options of the comparison may consider them equal all the same.
+	leftHashes := make([]string, len(leftItems))
+	for i, item := range leftItems {
+		leftHashes[i] = HashDocument(item, c.opts)
 	}
+	rightHashes := make([]string, len(rightItems))
+	for i, item := range rightItems {
+		rightHashes[i] = HashDocument(item, c.opts)
+	}
+	matches := longestCommonSubsequence(len(leftItems), len(rightItems), func(l, r int) bool {
+		if leftHashes[l] == rightHashes[r] {
+			return true
+		}
+		_, ok := c.probe(leftItems[l], rightItems[r])
+		return ok
+	})
 
+	diffs := make([]*Diff, 0)
+	il, ir := 0, 0
+	for ml := 0; ml <= len(leftItems); ml++ {
+		mr := len(rightItems)
+		if ml < len(leftItems) {
+			if matches[ml] == -1 {
+				continue
+			}
+			mr = matches[ml]
+		}
+		// The items between the previous and the current aligned pairs are compared in their order,
+		// and the remaining items of the longer side are added or deleted.
+		for i := 0; il+i < ml || ir+i < mr; i++ {
+			var leftValue, rightValue ast.Node
+			if il+i < ml {
+				leftValue = leftItems[il+i]
+			}
+			if ir+i < mr {
+				rightValue = rightItems[ir+i]
+			}
+			diffs = append(diffs, c.compareNodes(leftValue, rightValue)...)
+		}
+		if ml < len(leftItems) {
+			// The aligned items are equal, but their comments may still differ.
+			diffs = append(diffs, c.compareNodes(leftItems[ml], rightItems[mr])...)
+		}
+		il, ir = ml+1, mr+1
+	}
 	return diffs
 }
 
-func ignoreIndexes(diffs []*Diff, opts DiffOptions) []*Diff {
-	leftNodes := make([]ast.Node, len(diffs))
-	rightNodes := make([]ast.Node, len(diffs))
-	for i, diff := range diffs {
-		leftNodes[i] = diff.leftNode
-		rightNodes[i] = diff.rightNode
+// longestCommonSubsequence returns the index of the right value that each left value is aligned with, or -1 if there is none,
+// so that the most values that are equal by the function are aligned in their order. The common prefix and suffix are aligned first,
+// and the rest takes time and memory proportional to the product of the lengths of the remaining values,
+// where each pair of them is tested once.
+func longestCommonSubsequence(leftLen, rightLen int, equal func(l, r int) bool) []int {
+	matches := make([]int, leftLen)
+	for i := range matches {
+		matches[i] = -1
+	}
+	prefix := 0
+	for prefix < leftLen && prefix < rightLen && equal(prefix, prefix) {
+		matches[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < leftLen-prefix && suffix < rightLen-prefix && equal(leftLen-1-suffix, rightLen-1-suffix) {
+		matches[leftLen-1-suffix] = rightLen - 1 - suffix
+		suffix++
 	}
 
-	for il, leftNode := range leftNodes {
-		if leftNode == nil {
+	l := leftLen - prefix - suffix
+	r := rightLen - prefix - suffix
+	// lengths[i*(r+1)+j] is the length of the longest common subsequence of the remaining values from i and j on,
+	// and equals[i*(r+1)+j] holds whether the values at i and j are equal.
+	width := r + 1
+	lengths := make([]int, (l+1)*width)
+	equals := make([]bool, (l+1)*width)
+	for i := l - 1; i >= 0; i-- {
+		for j := r - 1; j >= 0; j-- {
+			if equals[i*width+j] = equal(prefix+i, prefix+j); equals[i*width+j] {
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			} else {
+				lengths[i*width+j] = max(lengths[(i+1)*width+j], lengths[i*width+j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < l && j < r; {
+		switch {
+		case equals[i*width+j]:
+			matches[prefix+i] = prefix + j
+			i++
+			j++
+		case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+// ignoredIndices returns the indices of the sequences that are excluded from the comparison by the IgnoreIndices option.
+func (c *comparator) ignoredIndices(leftNode, rightNode *ast.SequenceNode) map[int]bool {
+	ignored := make(map[int]bool)
+	if len(c.opts.IgnoreIndices) == 0 {
+		return ignored
+	}
+	leftPath := GetNodePath(leftNode)
+	rightPath := GetNodePath(rightNode)
+	for path, indices := range c.opts.IgnoreIndices {
+		if ComparePaths(path, leftPath) != 0 && ComparePaths(path, rightPath) != 0 {
 			continue
 		}
-		for ir, rightNode := range rightNodes {
-			if rightNode == nil {
-				continue
-			}
-			if len(compareNodes(leftNode, rightNode, opts)) == 0 {
-				leftNodes[il] = nil
-				rightNodes[ir] = nil
+		for _, i := range indices {
+			ignored[i] = true
+		}
+	}
+	return ignored
+}
+
+func withoutIgnoredIndices(values []ast.Node, ignored map[int]bool) []ast.Node {
+	if len(ignored) == 0 {
+		return values
+	}
+	filtered := make([]ast.Node, 0, len(values))
+	for i, value := range values {
+		if !ignored[i] {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
+
+// sequenceKeys returns the keys that may identify the items of the sequences, which are the ones of the KeyedSequences
+// whose paths match the path of the sequences, followed by the MatchByKeys.
+func (c *comparator) sequenceKeys(leftNode, rightNode *ast.SequenceNode) []string {
+	if len(c.opts.KeyedSequences) == 0 {
+		return c.opts.MatchByKeys
+	}
+	leftPath := splitPath(GetNodePath(leftNode))
+	rightPath := splitPath(GetNodePath(rightNode))
+	patterns := make([]string, 0, len(c.opts.KeyedSequences))
+	for pattern := range c.opts.KeyedSequences {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	keys := make([]string, 0, len(patterns)+len(c.opts.MatchByKeys))
+	for _, pattern := range patterns {
+		segments := splitPath(pattern)
+		if matchExactPathPattern(segments, leftPath) || matchExactPathPattern(segments, rightPath) {
+			keys = append(keys, c.opts.KeyedSequences[pattern])
+		}
+	}
+	return append(keys, c.opts.MatchByKeys...)
+}
+
+// sequenceMatchKey returns the first one of the keys that every item of both sequences is a mapping with,
+// holding a scalar value which is unique within its sequence.
+func (c *comparator) sequenceMatchKey(keys []string, leftItems, rightItems []ast.Node) (string, bool) {
+	if len(leftItems) == 0 && len(rightItems) == 0 {
+		return "", false
+	}
+	for _, key := range keys {
+		_, leftOk := c.itemsByKey(leftItems, key)
+		_, rightOk := c.itemsByKey(rightItems, key)
+		if leftOk && rightOk {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// itemsByKey maps the values of the key to the indexes of the items holding them.
+// It reports false if any one of the items is not a mapping with a scalar value for the key, or the values are not unique.
+func (c *comparator) itemsByKey(items []ast.Node, key string) (map[string]int, bool) {
+	indexes := make(map[string]int, len(items))
+	for i, item := range items {
+		value, ok := scalarValue(childNode(wrapMappingValueNode(item), pathSegment{key: key}, c.opts))
+		if !ok {
+			return nil, false
+		}
+		if _, ok := indexes[value]; ok {
+			return nil, false
+		}
+		indexes[value] = i
+	}
+	return indexes, true
+}
+
+// compareKeyedSequenceNodes compares the sequences of mappings by matching their items that hold the same value for the key,
+// regardless of the order of the items. The items without a match on the other side are reported as deleted or added.
+func (c *comparator) compareKeyedSequenceNodes(leftItems, rightItems []ast.Node, key string) []*Diff {
+	leftIndexes, _ := c.itemsByKey(leftItems, key)
+	rightIndexes, _ := c.itemsByKey(rightItems, key)
+
+	diffs := make([]*Diff, 0)
+	matches := make([]int, 0, len(leftItems))
+	for _, leftValue := range leftItems {
+		value, _ := scalarValue(childNode(wrapMappingValueNode(leftValue), pathSegment{key: key}, c.opts))
+		ir, ok := rightIndexes[value]
+		if !ok {
+			matches = append(matches, -1)
+			diffs = append(diffs, c.compareNodes(leftValue, nil)...)
+			continue
+		}
+		matches = append(matches, ir)
+		diffs = append(diffs, c.compareNodes(leftValue, rightItems[ir])...)
+	}
+	for _, rightValue := range rightItems {
+		value, _ := scalarValue(childNode(wrapMappingValueNode(rightValue), pathSegment{key: key}, c.opts))
+		if _, ok := leftIndexes[value]; !ok {
+			diffs = append(diffs, c.compareNodes(nil, rightValue)...)
+		}
+	}
+
+	if isReordered(matches) {
+		c.reorderedSequences++
+	}
+	diffs = append(diffs, c.movedDiffs(leftItems, rightItems, matches)...)
+
+	return diffs
+}
+
+// compareUnorderedSequenceNodes compares the sequences regardless of the order of their items.
+// Each item is matched with an equal item on the other side, and the remaining items are compared in their order.
+func (c *comparator) compareUnorderedSequenceNodes(leftNode, rightNode *ast.SequenceNode) []*Diff {
+	ignored := c.ignoredIndices(leftNode, rightNode)
+	leftItems := withoutIgnoredIndices(leftNode.Values, ignored)
+	rightItems := withoutIgnoredIndices(rightNode.Values, ignored)
+
+	// The items are hashed regardless of the order of mapping keys, so the right items with the same hash as a left item
+	// are tried first, as they are known to be equal to it, which avoids probing all the other items.
+	rightIndexesByHash := make(map[string][]int)
+	for ir, rightValue := range rightItems {
+		hash := HashDocument(rightValue, c.opts)
+		rightIndexesByHash[hash] = append(rightIndexesByHash[hash], ir)
+	}
+
+	matches := make([]int, len(leftItems))
+	matchedRight := make([]bool, len(rightItems))
+	match := func(il, ir int) bool {
+		if matchedRight[ir] {
+			return false
+		}
+		p, ok := c.probe(leftItems[il], rightItems[ir])
+		if ok {
+			c.merge(p)
+			matches[il] = ir
+			matchedRight[ir] = true
+		}
+		return ok
+	}
+	for il, leftValue := range leftItems {
+		matches[il] = -1
+		matched := false
+		for _, ir := range rightIndexesByHash[HashDocument(leftValue, c.opts)] {
+			if matched = match(il, ir); matched {
 				break
 			}
 		}
+		for ir := 0; ir < len(rightItems) && !matched; ir++ {
+			matched = match(il, ir)
+		}
+	}
+
+	if isReordered(matches) {
+		c.reorderedSequences++
+	}
+	diffs := c.movedDiffs(leftItems, rightItems, matches)
+
+	leftValues := make([]ast.Node, 0)
+	for il, leftValue := range leftItems {
+		if matches[il] == -1 {
+			leftValues = append(leftValues, leftValue)
+		}
 	}
+	rightValues := make([]ast.Node, 0)
+	for ir, rightValue := range rightItems {
+		if !matchedRight[ir] {
+			rightValues = append(rightValues, rightValue)
+		}
+	}
+
+	l := max(len(leftValues), len(rightValues))
+	for i := 0; i < l; i++ {
+		var leftValue, rightValue ast.Node
+		if len(leftValues) > i {
+			leftValue = leftValues[i]
+		}
+		if len(rightValues) > i {
+			rightValue = rightValues[i]
+		}
+		diffs = append(diffs, c.compareNodes(leftValue, rightValue)...)
+	}
+
+	return diffs
+}
+
+// isReordered reports whether the matched items appear in a different relative order on the right side.
+// The matches hold the index of the matched right item for each left item, or -1 if there is none.
+// Items that are only shifted by insertions or deletions are not considered reordered.
+func isReordered(matches []int) bool {
+	last := -1
+	for _, ir := range matches {
+		if ir == -1 {
+			continue
+		}
+		if ir < last {
+			return true
+		}
+		last = ir
+	}
+	return false
+}
+
+// movedDiffs returns the Moved differences of the matched items that are out of order, if DetectMoves is set.
+// The matches hold the index of the matched right item for each left item, or -1 if there is none.
+// The items in the longest run that keeps its order on both sides are not moved, so the fewest moves are reported.
+func (c *comparator) movedDiffs(leftItems, rightItems []ast.Node, matches []int) []*Diff {
+	if !c.opts.DetectMoves {
+		return nil
+	}
+	kept := inOrderMatches(matches)
+	diffs := make([]*Diff, 0)
+	for il, ir := range matches {
+		if ir == -1 || kept[il] {
+			continue
+		}
+		leftValue := wrapMappingValueNode(leftItems[il])
+		rightValue := wrapMappingValueNode(rightItems[ir])
+		diffs = append(diffs, &Diff{
+			leftNode:  leftValue,
+			rightNode: rightValue,
+			moved:     true,
+			from:      sequenceIndex(leftValue),
+			to:        sequenceIndex(rightValue),
+		})
+	}
+	return diffs
+}
 
-	resultDiffs := make([]*Diff, 0)
-	for i := range diffs {
-		leftNode := leftNodes[i]
-		rightNode := rightNodes[i]
-		if leftNode == nil && rightNode == nil {
+// inOrderMatches returns the left items of the longest increasing subsequence of the matches,
+// which are the most items that keep their relative order on both sides.
+func inOrderMatches(matches []int) map[int]bool {
+	// tails holds the left index of the last item of the longest subsequence of each length found so far,
+	// and previous links each item to the one before it in its subsequence.
+	tails := make([]int, 0, len(matches))
+	previous := make([]int, len(matches))
+	for il, ir := range matches {
+		if ir == -1 {
 			continue
 		}
-		resultDiffs = append(resultDiffs, &Diff{leftNode, rightNode})
+		n := sort.Search(len(tails), func(i int) bool { return matches[tails[i]] >= ir })
+		previous[il] = -1
+		if n > 0 {
+			previous[il] = tails[n-1]
+		}
+		if n == len(tails) {
+			tails = append(tails, il)
+		} else {
+			tails[n] = il
+		}
 	}
 
-	return resultDiffs
+	kept := make(map[int]bool, len(tails))
+	if len(tails) == 0 {
+		return kept
+	}
+	for il := tails[len(tails)-1]; il != -1; il = previous[il] {
+		kept[il] = true
+	}
+	return kept
 }
 
-func nodePathString(n ast.Node) string {
-	path := n.GetPath()[2:]
-	// Path of the MappingNode points to the first key in the map.
-	if n.Type() == ast.MappingType {
-		path = path[:strings.LastIndex(path, ".")]
+// sequenceIndex returns the index of the item in its sequence, which is the last segment of its path.
+func sequenceIndex(n ast.Node) int {
+	segments := splitPath(GetNodePath(n))
+	if len(segments) == 0 {
+		return 0
+	}
+	return segments[len(segments)-1].index
+}
+
+// GetNodePath returns the path of the node as it is written in the differences, such as "spec.containers[0].image",
+// which is the path of the parser without the leading "$.", or "$" for the root node.
+// The path of a mapping is the path of the mapping itself, rather than the path of its first key as the parser sets it.
+func GetNodePath(n ast.Node) string {
+	path := n.GetPath()
+	// Path of the block MappingNode points to the first key in the map, whereas a flow mapping has its own path.
+	if m, ok := n.(*ast.MappingNode); ok && !m.IsFlowStyle {
+		path = trimLastPathSegment(path)
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return "$"
 	}
 	return path
 }
 
-func nodeValueString(n ast.Node) string {
+// trimLastPathSegment removes the last key or index from the path, taking the quoted keys into account.
+func trimLastPathSegment(path string) string {
+	switch {
+	case strings.HasSuffix(path, "]"):
+		return path[:strings.LastIndexByte(path, '[')]
+	case strings.HasSuffix(path, "'") && len(path) > 1:
+		path = path[:strings.LastIndexByte(path[:len(path)-1], '\'')+1]
+		return strings.TrimSuffix(path[:len(path)-1], ".")
+	}
+	i := strings.LastIndexAny(path, ".[")
+	if i == -1 {
+		return ""
+	}
+	return path[:i]
+}
+
+func nodeValueString(n ast.Node, opts FormatOptions) string {
+	return truncateValueLines(fullNodeValueString(n, opts), opts.MaxValueLines)
+}
+
+// truncateValueLines keeps the first lines of the rendered value, noting the number of the others after them,
+// such as "... (+3 more lines)", if the value has more lines than the limit and the limit is positive.
+// The empty line that a collection starts with does not count.
+func truncateValueLines(value string, maxLines int) string {
+	if maxLines <= 0 {
+		return value
+	}
+	prefix := ""
+	if strings.HasPrefix(value, "\n") {
+		prefix, value = "\n", value[1:]
+	}
+	lines := strings.Split(value, "\n")
+	if len(lines) <= maxLines {
+		return prefix + value
+	}
+	more := len(lines) - maxLines
+	unit := "lines"
+	if more == 1 {
+		unit = "line"
+	}
+	return fmt.Sprintf("%s%s\n  ... (+%d more %s)", prefix, strings.Join(lines[:maxLines], "\n"), more, unit)
+}
+
+func fullNodeValueString(n ast.Node, opts FormatOptions) string {
+	if opts.EmptyPlaceholder != "" && isEmptyNode(n) {
+		return opts.EmptyPlaceholder
+	}
 	switch n.Type() {
 	case ast.MappingType, ast.SequenceType:
 		indent := n.GetToken().Position.IndentNum
@@ -197,11 +1132,151 @@ func nodeValueString(n ast.Node) string {
 		}
 		s = strings.Join(lines, "\n")
 		return fmt.Sprintf("\n%s", s)
+	case ast.LiteralType:
+		return literalValueString(n.(*ast.LiteralNode))
+	case ast.StringType:
+		if !opts.PreserveQuotes {
+			return n.String()
+		}
+		s, ok := rawStringValue(n.(*ast.StringNode))
+		if !ok {
+			return n.String()
+		}
+		if comment := n.GetComment(); comment != nil {
+			s = fmt.Sprintf("%s %s", s, comment.String())
+		}
+		return s
 	default:
 		return n.String()
 	}
 }
 
+// literalValueString renders the block scalar with its indicator, such as "|" or ">-",
+// followed by the lines of its content as they are written in the source,
+// each one indented in the same way as the values of collections.
+func literalValueString(n *ast.LiteralNode) string {
+	origin := n.Value.GetToken().Origin
+	// The parser leaves the LF of the CRLF that ends the header at the start of the value, and the CRs are not rendered,
+	// as a terminal would return to the start of the line at them.
+	if strings.HasSuffix(n.Start.Origin, "\r") {
+		origin = strings.TrimPrefix(origin, "\n")
+	}
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(origin, "\r", ""), " \n"), "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent == -1 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent != -1 {
+			line = line[indent:]
+		}
+		lines[i] = strings.TrimRight(fmt.Sprintf("  %s", line), " ")
+	}
+	return fmt.Sprintf("%s\n%s", n.Start.Value, strings.Join(lines, "\n"))
+}
+
+// isEmptyNode reports whether the node holds a null or an empty string value.
+func isEmptyNode(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.NullNode:
+		return true
+	case *ast.StringNode:
+		return n.Value == ""
+	}
+	return false
+}
+
+// rawStringValue returns the string as it is written in the source, including its quotes and escapes,
+// which is kept by the origin of the token.
+// It reports false when the origin is incomplete, as it happens for some escape sequences like "\u00e9".
+func rawStringValue(n *ast.StringNode) (string, bool) {
+	s := strings.TrimSpace(n.Token.Origin)
+	if n.Token.Type != token.DoubleQuoteType {
+		return s, s != ""
+	}
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	// The closing quote must not be escaped by an odd number of backslashes.
+	backslashes := len(s[:len(s)-1]) - len(strings.TrimRight(s[:len(s)-1], `\`))
+	return s, backslashes%2 == 0
+}
+
 func nodeMetadata(n ast.Node) string {
-	return fmt.Sprintf("[line:%d <%s>]", n.GetToken().Position.Line, n.Type())
+	return fmt.Sprintf("[line:%s <%s>]", nodeLines(n), n.Type())
+}
+
+// nodeLines returns the line of the node, or the range of the lines of a collection that spans multiple lines, such as "14-20".
+func nodeLines(n ast.Node) string {
+	start, end := n.GetToken().Position.Line, n.GetToken().Position.Line
+	if n.Type() == ast.MappingType || n.Type() == ast.SequenceType {
+		start, end = nodeLineRange(n)
+	}
+	if start == end {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
+
+// addedOrDeletedNodeValue returns the value of the node, prefixed with the size of the collection when it is requested.
+func addedOrDeletedNodeValue(n ast.Node, opts FormatOptions) string {
+	value := nodeValueString(n, opts)
+	if !opts.CollectionSizes {
+		return value
+	}
+	switch n := n.(type) {
+	case *ast.MappingNode:
+		return collectionSize(len(n.Values), "key", "keys") + value
+	case *ast.SequenceNode:
+		return collectionSize(len(n.Values), "item", "items") + value
+	}
+	return value
+}
+
+func collectionSize(size int, singular, plural string) string {
+	if size == 1 {
+		return fmt.Sprintf("(1 %s)", singular)
+	}
+	return fmt.Sprintf("(%d %s)", size, plural)
+}
+
+// addedOrDeletedNodeMetadata returns the metadata of the node, extended with the size of the subtree for collections when it is requested.
+func addedOrDeletedNodeMetadata(n ast.Node, opts FormatOptions) string {
+	if !opts.ExtendedMetadata || (n.Type() != ast.MappingType && n.Type() != ast.SequenceType) {
+		return nodeMetadata(n)
+	}
+	leaves, depth := subtreeSize(n)
+	return fmt.Sprintf("[line:%s <%s> leaves:%d depth:%d]", nodeLines(n), n.Type(), leaves, depth)
+}
+
+// subtreeSize returns the number of leaves under the node, which are the scalars and the empty collections,
+// and the depth of the node, which is 0 for a scalar and one more than the deepest child for a collection.
+func subtreeSize(n ast.Node) (int, int) {
+	var children []ast.Node
+	switch n := wrapMappingValueNode(n).(type) {
+	case *ast.MappingNode:
+		for _, value := range n.Values {
+			children = append(children, value.Value)
+		}
+	case *ast.SequenceNode:
+		children = n.Values
+	default:
+		return 1, 0
+	}
+	if len(children) == 0 {
+		return 1, 1
+	}
+	leaves, depth := 0, 0
+	for _, child := range children {
+		childLeaves, childDepth := subtreeSize(child)
+		leaves += childLeaves
+		depth = max(depth, childDepth)
+	}
+	return leaves, depth + 1
 }
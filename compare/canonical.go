@@ -0,0 +1,34 @@
+package compare
+
+import (
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// canonicalizeFile re-serializes each document of the file through the yaml encoder, which sorts the mapping keys,
+// resolves the anchors, aliases and merge keys, and writes the scalars in their canonical form, such as 0x1F as 31.
+// The documents of the returned file hold the canonical forms, so their lines refer to them rather than to the source.
+func canonicalizeFile(f *ast.File) (*ast.File, error) {
+	canonical := &ast.File{Name: f.Name, Docs: make([]*ast.DocumentNode, 0, len(f.Docs))}
+	for _, doc := range documents(f) {
+		if doc == nil || doc.Body == nil {
+			canonical.Docs = append(canonical.Docs, doc)
+			continue
+		}
+		var v interface{}
+		if err := yaml.NodeToValue(doc.Body, &v); err != nil {
+			return nil, err
+		}
+		b, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		canonicalFile, err := parser.ParseBytes(b, 0)
+		if err != nil {
+			return nil, err
+		}
+		canonical.Docs = append(canonical.Docs, canonicalFile.Docs...)
+	}
+	return canonical, nil
+}
@@ -0,0 +1,39 @@
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ClosestMatch compares the candidate yaml file against each one of the baselines, which are keyed by their names,
+// and returns the name of the baseline that has the fewest differences with the candidate, along with these differences.
+// The baselines are the left side of the comparisons. Ties are broken by the order of the names, so the result is deterministic.
+func ClosestMatch(candidate []byte, baselines map[string][]byte, comments bool, opts DiffOptions) (string, FileDiffs, error) {
+	if len(baselines) == 0 {
+		return "", nil, errors.New("no baselines to match")
+	}
+
+	names := make([]string, 0, len(baselines))
+	for name := range baselines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var closestName string
+	var closestDiffs FileDiffs
+	closestTotal := -1
+	for _, name := range names {
+		diffs, err := Compare(baselines[name], candidate, comments, opts)
+		if err != nil {
+			return "", nil, fmt.Errorf("baseline %s: %w", name, err)
+		}
+		total := diffs.Stat().Total()
+		if closestTotal == -1 || total < closestTotal {
+			closestName = name
+			closestDiffs = diffs
+			closestTotal = total
+		}
+	}
+	return closestName, closestDiffs, nil
+}
@@ -0,0 +1,29 @@
+package compare
+
+import "github.com/fatih/color"
+
+// The color functions color the output regardless of color.NoColor, which is a process-wide setting,
+// so whether the output is colored only depends on FormatOptions.Plain of each call.
+var (
+	hiBlueString    = colorStringFunc(color.FgHiBlue)
+	hiCyanString    = colorStringFunc(color.FgHiCyan)
+	hiGreenString   = colorStringFunc(color.FgHiGreen)
+	hiMagentaString = colorStringFunc(color.FgHiMagenta)
+	hiRedString     = colorStringFunc(color.FgHiRed)
+	hiWhiteString   = colorStringFunc(color.FgHiWhite)
+	hiYellowString  = colorStringFunc(color.FgHiYellow)
+)
+
+// colorStringFunc returns a function that formats the string in the color, like color.HiGreenString does,
+// but with the color enabled on its own. Without arguments, the string is printed as is, so the values in it,
+// such as "100%", are not read as format verbs.
+func colorStringFunc(attribute color.Attribute) func(format string, a ...interface{}) string {
+	return func(format string, a ...interface{}) string {
+		c := color.New(attribute)
+		c.EnableColor()
+		if len(a) == 0 {
+			return c.Sprint(format)
+		}
+		return c.Sprintf(format, a...)
+	}
+}
@@ -1,36 +1,205 @@
 package compare
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
-	"github.com/fatih/color"
+	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
 	"github.com/goccy/go-yaml/parser"
 )
 
+// DiffType represents the kind of a difference between two nodes.
+type DiffType int
+
+const (
+	// Added means the node exists only in the right document.
+	Added DiffType = iota
+	// Deleted means the node exists only in the left document.
+	Deleted
+	// Modified means the node exists in both documents with different values.
+	Modified
+	// Moved means an item of a sequence exists in both documents at different indexes, which is only reported with DetectMoves.
+	Moved
+	// Renamed means a key of a mapping is replaced by another key with an equal value, which is only reported with DetectRenames.
+	Renamed
+)
+
+// String returns "added", "deleted", "modified", "moved" or "renamed", or "unknown" if the type is none of them.
+func (t DiffType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Deleted:
+		return "deleted"
+	case Modified:
+		return "modified"
+	case Moved:
+		return "moved"
+	case Renamed:
+		return "renamed"
+	}
+	return "unknown"
+}
+
 type Diff struct {
 	leftNode  ast.Node
 	rightNode ast.Node
+
+	// path overrides the path of the nodes when it is set by WithPath, or when the nodes are reached through an alias
+	// or a merge key, whose nodes have the paths of their anchors.
+	path string
+	// comment is set when the values of the nodes are equal and only their comments differ.
+	comment bool
+	// keyOrder is set when the nodes are equal mappings whose keys are in a different order.
+	keyOrder bool
+	// moved is set when the nodes are the items of a sequence that move from the index from to the index to.
+	moved    bool
+	from, to int
+	// renamed is set when the nodes are the equal values of a key on the left side and another key on the right side.
+	renamed bool
+	// sequence is the outermost sequence on the right side that the nodes are under, if any,
+	// which a merge patch replaces as a whole.
+	sequence ast.Node
+}
+
+// Type returns the kind of the difference.
+func (d *Diff) Type() DiffType {
+	if d.moved {
+		return Moved
+	}
+	if d.renamed {
+		return Renamed
+	}
+	if d.leftNode == nil {
+		return Added
+	}
+	if d.rightNode == nil {
+		return Deleted
+	}
+	return Modified
+}
+
+// MovedIndexes returns the indexes of the moved item on the left and right sides, or false if the difference is not a move.
+func (d *Diff) MovedIndexes() (int, int, bool) {
+	if !d.moved {
+		return 0, 0, false
+	}
+	return d.from, d.to, true
+}
+
+// RenamedPath returns the path of the renamed key on the right side, or false if the difference is not a rename.
+// The path on the left side is the one that Path returns.
+func (d *Diff) RenamedPath() (string, bool) {
+	if !d.renamed {
+		return "", false
+	}
+	return GetNodePath(d.rightNode), true
+}
+
+// OldValue returns the value of the node on the left side as the output renders it, such as "1" or "'quoted'",
+// where a collection starts on a new line with its items indented, or an empty string if the difference is an addition.
+func (d *Diff) OldValue() string {
+	if d.leftNode == nil {
+		return ""
+	}
+	return nodeValueString(d.leftNode, DefaultOutputOptions)
+}
+
+// NewValue returns the value of the node on the right side as the output renders it,
+// or an empty string if the difference is a deletion.
+func (d *Diff) NewValue() string {
+	if d.rightNode == nil {
+		return ""
+	}
+	return nodeValueString(d.rightNode, DefaultOutputOptions)
+}
+
+// WithPath returns a copy of the difference that is rendered with the given path instead of the path of its nodes,
+// which allows relabeling the paths, such as in FormatOptions.Transform.
+func (d *Diff) WithPath(path string) *Diff {
+	c := *d
+	c.path = path
+	return &c
+}
+
+// ModifiedKind represents how the value of a modified node changes.
+type ModifiedKind int
+
+const (
+	// NotModified means the difference is not a modification, but an addition or a deletion.
+	NotModified ModifiedKind = iota
+	// ValueChange means the node keeps its type and only its value changes, such as 1 becoming 2,
+	// or a mapping whose differences are collapsed by MaxDepth. Plain and block strings are considered the same type.
+	ValueChange
+	// TypeChange means a scalar changes its type, or a scalar becomes a collection or vice versa, such as 1 becoming "1".
+	TypeChange
+	// CollectionRestructure means a collection changes its kind, such as a mapping becoming a sequence.
+	CollectionRestructure
+)
+
+// ModifiedKind returns how the value of the node changes, or NotModified if the difference is not a modification.
+func (d *Diff) ModifiedKind() ModifiedKind {
+	if d.Type() != Modified {
+		return NotModified
+	}
+	leftCollection := isCollectionNode(d.leftNode)
+	rightCollection := isCollectionNode(d.rightNode)
+	switch {
+	case leftCollection && rightCollection && d.leftNode.Type() != d.rightNode.Type():
+		return CollectionRestructure
+	case d.leftNode.Type() == d.rightNode.Type(), isStringNode(d.leftNode) && isStringNode(d.rightNode):
+		return ValueChange
+	}
+	return TypeChange
+}
+
+// IsTypeChange reports whether the difference is a modification whose nodes have different types,
+// such as "8080" becoming 8080 or a mapping becoming a sequence. Plain and block strings are considered the same type.
+func (d *Diff) IsTypeChange() bool {
+	if d.Type() != Modified {
+		return false
+	}
+	return d.leftNode.Type() != d.rightNode.Type() && !(isStringNode(d.leftNode) && isStringNode(d.rightNode))
+}
+
+// Path returns the path of the node that differs, such as "spec.containers[0].image".
+// The path of a moved item or a renamed key is the one on the left side.
+func (d *Diff) Path() string {
+	if d.path != "" {
+		return d.path
+	}
+	if d.leftNode != nil {
+		return GetNodePath(d.leftNode)
+	}
+	return GetNodePath(d.rightNode)
 }
 
 func (d *Diff) Format(opts FormatOptions) string {
 	var b strings.Builder
-	if d.leftNode == nil { // Added
+	switch d.Type() {
+	case Added:
 		sign := "+"
-		path := nodePathString(d.rightNode)
-		value := nodeValueString(d.rightNode)
-		metadata := nodeMetadata(d.rightNode)
+		path := formatPath(d.Path(), opts.PathStyle)
+		value := addedOrDeletedNodeValue(d.rightNode, opts)
+		metadata := addedOrDeletedNodeMetadata(d.rightNode, opts)
 
 		if !opts.Plain {
-			sign = color.HiGreenString(sign)
-			path = color.HiGreenString(path)
-			value = color.HiWhiteString(value)
-			metadata = color.HiCyanString(metadata)
+			sign = hiGreenString(sign)
+			path = hiGreenString(path)
+			value = hiWhiteString(value)
+			metadata = hiCyanString(metadata)
 		}
 
-		if opts.Silent {
+		if opts.PathsOnly {
 			b.WriteString(fmt.Sprintf("%s %s", sign, path))
 		} else {
 			if opts.Metadata {
@@ -40,20 +209,20 @@ func (d *Diff) Format(opts FormatOptions) string {
 			}
 		}
 
-	} else if d.rightNode == nil { //Deleted
+	case Deleted:
 		sign := "-"
-		path := nodePathString(d.leftNode)
-		value := nodeValueString(d.leftNode)
-		metadata := nodeMetadata(d.leftNode)
+		path := formatPath(d.Path(), opts.PathStyle)
+		value := addedOrDeletedNodeValue(d.leftNode, opts)
+		metadata := addedOrDeletedNodeMetadata(d.leftNode, opts)
 
 		if !opts.Plain {
-			sign = color.HiRedString(sign)
-			path = color.HiRedString(path)
-			value = color.HiWhiteString(value)
-			metadata = color.HiCyanString(metadata)
+			sign = hiRedString(sign)
+			path = hiRedString(path)
+			value = hiWhiteString(value)
+			metadata = hiCyanString(metadata)
 		}
 
-		if opts.Silent {
+		if opts.PathsOnly {
 			b.WriteString(fmt.Sprintf("%s %s", sign, path))
 		} else {
 			if opts.Metadata {
@@ -62,24 +231,35 @@ func (d *Diff) Format(opts FormatOptions) string {
 				b.WriteString(fmt.Sprintf("%s %s: %s", sign, path, value))
 			}
 		}
-	} else { //Modified
+	case Modified:
 		sign := "~"
-		path := nodePathString(d.leftNode)
-		leftValue := nodeValueString(d.leftNode)
-		rightValue := nodeValueString(d.rightNode)
+		path := formatPath(d.Path(), opts.PathStyle)
+		leftValue := nodeValueString(d.leftNode, opts)
+		rightValue := nodeValueString(d.rightNode, opts)
+		if d.comment {
+			leftValue = commentString(d.leftNode)
+			rightValue = commentString(d.rightNode)
+		}
+		if d.keyOrder {
+			leftValue = keyOrderString(d.leftNode)
+			rightValue = keyOrderString(d.rightNode)
+		}
 		leftMetadata := nodeMetadata(d.leftNode)
 		rightMetadata := nodeMetadata(d.rightNode)
+		if d.IsTypeChange() {
+			rightMetadata += " (type changed)"
+		}
 
 		if !opts.Plain {
-			sign = color.HiYellowString(sign)
-			path = color.HiYellowString(path)
-			leftValue = color.HiWhiteString(leftValue)
-			rightValue = color.HiWhiteString(rightValue)
-			leftMetadata = color.HiCyanString(leftMetadata)
-			rightMetadata = color.HiCyanString(rightMetadata)
+			sign = hiYellowString(sign)
+			path = hiYellowString(path)
+			leftValue = hiWhiteString(leftValue)
+			rightValue = hiWhiteString(rightValue)
+			leftMetadata = hiCyanString(leftMetadata)
+			rightMetadata = hiCyanString(rightMetadata)
 		}
 
-		if opts.Silent {
+		if opts.PathsOnly {
 			b.WriteString(fmt.Sprintf("%s %s", sign, path))
 		} else {
 			if opts.Metadata {
@@ -88,10 +268,147 @@ func (d *Diff) Format(opts FormatOptions) string {
 				b.WriteString(fmt.Sprintf("%s %s: %s -> %s", sign, path, leftValue, rightValue))
 			}
 		}
+	case Moved:
+		sign := "↕"
+		if opts.ASCIISymbols {
+			sign = "^"
+		}
+		path := movedPath(formatPath(d.Path(), opts.PathStyle), d.from, d.to, arrow(opts))
+		value := nodeValueString(d.rightNode, opts)
+		metadata := nodeMetadata(d.rightNode)
+
+		if !opts.Plain {
+			sign = hiBlueString(sign)
+			path = hiBlueString(path)
+			value = hiWhiteString(value)
+			metadata = hiCyanString(metadata)
+		}
+
+		if opts.PathsOnly {
+			b.WriteString(fmt.Sprintf("%s %s", sign, path))
+		} else {
+			if opts.Metadata {
+				b.WriteString(fmt.Sprintf("%s %s: %s %s", sign, path, metadata, value))
+			} else {
+				b.WriteString(fmt.Sprintf("%s %s: %s", sign, path, value))
+			}
+		}
+	case Renamed:
+		sign := "⟳"
+		if opts.ASCIISymbols {
+			sign = "="
+		}
+		newPath, _ := d.RenamedPath()
+		path := formatPath(d.Path(), opts.PathStyle) + " " + arrow(opts) + " " + formatPath(newPath, opts.PathStyle)
+		value := nodeValueString(d.rightNode, opts)
+		metadata := nodeMetadata(d.rightNode)
+
+		if !opts.Plain {
+			sign = hiMagentaString(sign)
+			path = hiMagentaString(path)
+			value = hiWhiteString(value)
+			metadata = hiCyanString(metadata)
+		}
+
+		if opts.PathsOnly {
+			b.WriteString(fmt.Sprintf("%s %s", sign, path))
+		} else {
+			if opts.Metadata {
+				b.WriteString(fmt.Sprintf("%s %s: %s %s", sign, path, metadata, value))
+			} else {
+				b.WriteString(fmt.Sprintf("%s %s: %s", sign, path, value))
+			}
+		}
 	}
 	return b.String()
 }
 
+// movedPath replaces the index of the moved item at the end of the formatted path with both of its indexes
+// joined by the arrow, such as "items[0→3]" or "/items/0→3".
+func movedPath(path string, from, to int, arrow string) string {
+	for _, format := range []string{"[%d]", "/%d"} {
+		index := fmt.Sprintf(format, from)
+		if strings.HasSuffix(path, index) {
+			return strings.TrimSuffix(path, index) + fmt.Sprintf(strings.Replace(format, "%d", "%d"+arrow+"%d", 1), from, to)
+		}
+	}
+	return path
+}
+
+// arrow returns the arrow that joins the old and the new paths of the moved items and the renamed keys.
+func arrow(opts FormatOptions) string {
+	if opts.ASCIISymbols {
+		return "->"
+	}
+	return "→"
+}
+
+// DiffCount holds the number of differences by their type.
+type DiffCount struct {
+	Added    int
+	Deleted  int
+	Modified int
+	Moved    int
+	Renamed  int
+}
+
+// Total returns the number of all differences.
+func (c DiffCount) Total() int {
+	return c.Added + c.Deleted + c.Modified + c.Moved + c.Renamed
+}
+
+// String returns the counts in the form of "1 added, 2 deleted, 3 modified",
+// followed by the numbers of moved items and renamed keys, such as ", 4 moved" or ", 5 renamed", if there are any.
+func (c DiffCount) String() string {
+	s := fmt.Sprintf("%d added, %d deleted, %d modified", c.Added, c.Deleted, c.Modified)
+	if c.Moved > 0 {
+		s += fmt.Sprintf(", %d moved", c.Moved)
+	}
+	if c.Renamed > 0 {
+		s += fmt.Sprintf(", %d renamed", c.Renamed)
+	}
+	return s
+}
+
+func (c DiffCount) add(other DiffCount) DiffCount {
+	return DiffCount{
+		Added:    c.Added + other.Added,
+		Deleted:  c.Deleted + other.Deleted,
+		Modified: c.Modified + other.Modified,
+		Moved:    c.Moved + other.Moved,
+		Renamed:  c.Renamed + other.Renamed,
+	}
+}
+
+// Stat returns the number of differences by their type across all documents.
+func (d FileDiffs) Stat() DiffCount {
+	count := DiffCount{}
+	for _, docDiffs := range d {
+		count = count.add(docDiffs.Stat())
+	}
+	return count
+}
+
+// Stat returns the number of differences by their type in the document.
+func (d DocDiffs) Stat() DiffCount {
+	count := DiffCount{}
+	for _, diff := range d {
+		switch diff.Type() {
+		case Added:
+			count.Added++
+		case Deleted:
+			count.Deleted++
+		case Modified:
+			count.Modified++
+		case Moved:
+			count.Moved++
+		case Renamed:
+			count.Renamed++
+		}
+	}
+	return count
+}
+
 type DocDiffs []*Diff
 
 func (a DocDiffs) Len() int {
@@ -120,94 +437,617 @@ func (a DocDiffs) Less(i, j int) bool {
 		nodeB = diffB.rightNode
 	}
 
-	//todo: it is still partial ordered, which can cause the inconsistent order of items after sorting
-	if nodeA.GetToken().Position.Line == nodeB.GetToken().Position.Line {
+	positionA := nodeA.GetToken().Position
+	positionB := nodeB.GetToken().Position
+	if positionA.Line != positionB.Line {
+		return positionA.Line < positionB.Line
+	}
+	// The differences on the same line are ordered by their columns,
+	// and the ones that exist on the left side come before the additions.
+	if (diffA.leftNode != nil) != (diffB.leftNode != nil) {
 		return diffA.leftNode != nil
 	}
+	return positionA.Column < positionB.Column
+}
+
+// sort sorts the differences by their positions, breaking the ties by their paths if byPath is set,
+// so the differences at the same position, which otherwise keep the order they are found in, have a deterministic order.
+func (d DocDiffs) sort(byPath bool) {
+	if !byPath {
+		sort.Stable(d)
+		return
+	}
+	sort.SliceStable(d, func(i, j int) bool {
+		if d.Less(i, j) || d.Less(j, i) {
+			return d.Less(i, j)
+		}
+		return ComparePaths(d[i].Path(), d[j].Path()) < 0
+	})
+}
 
-	return nodeA.GetToken().Position.Line < nodeB.GetToken().Position.Line
+// SortByPath sorts the differences by their paths, which gives a deterministic order regardless of the source lines.
+// Sequence indexes are ordered numerically, as ComparePaths does.
+func (d DocDiffs) SortByPath() {
+	sort.SliceStable(d, func(i, j int) bool {
+		if c := ComparePaths(d[i].Path(), d[j].Path()); c != 0 {
+			return c < 0
+		}
+		return d[i].Type() < d[j].Type()
+	})
 }
 
 func (d DocDiffs) Format(opts FormatOptions) string {
-	diffsStrings := make([]string, 0, len(d))
+	if opts.Transform != nil {
+		d = d.transform(opts.Transform)
+	}
+	if opts.CountsOnly {
+		return d.Stat().String()
+	}
+	collapsed := d.collapseByPrefix(opts.CollapseDepth)
+	var s string
+	if opts.GroupByType {
+		s = d.formatGroupedByType(collapsed, opts)
+	} else {
+		diffsStrings := make([]string, 0, len(d))
+		for _, diff := range d {
+			if line, ok := formatCollapsed(diff, collapsed, opts); ok {
+				diffsStrings = append(diffsStrings, line)
+			}
+		}
+		s = strings.Join(diffsStrings, "\n")
+	}
+	if opts.IncludeCounts {
+		s = strings.TrimSuffix(d.Stat().String()+"\n"+s, "\n")
+	}
+	return s
+}
+
+// Paths returns the paths of the differences in their order, as the PathsOnly output lists them.
+func (d DocDiffs) Paths() []string {
+	paths := make([]string, 0, len(d))
 	for _, diff := range d {
-		diffsStrings = append(diffsStrings, diff.Format(opts))
+		paths = append(paths, diff.Path())
 	}
-	return strings.Join(diffsStrings, "\n")
+	return paths
+}
+
+// Filter returns the differences of the given types, or all of them if no type is given, without modifying the differences.
+func (d DocDiffs) Filter(types ...DiffType) DocDiffs {
+	filtered := make(DocDiffs, 0, len(d))
+	for _, diff := range d {
+		if len(types) == 0 || slices.Contains(types, diff.Type()) {
+			filtered = append(filtered, diff)
+		}
+	}
+	return filtered
+}
+
+// GroupByType returns the differences grouped by their type, keeping their order within each group.
+// Only the types with at least one difference have a group.
+func (d DocDiffs) GroupByType() map[DiffType]DocDiffs {
+	groups := make(map[DiffType]DocDiffs)
+	for _, diff := range d {
+		groups[diff.Type()] = append(groups[diff.Type()], diff)
+	}
+	return groups
+}
+
+// transform returns the differences that are rewritten by the function, leaving out the ones that it drops.
+func (d DocDiffs) transform(fn func(*Diff) (*Diff, bool)) DocDiffs {
+	transformed := make(DocDiffs, 0, len(d))
+	for _, diff := range d {
+		if diff, ok := fn(diff); ok {
+			transformed = append(transformed, diff)
+		}
+	}
+	return transformed
+}
+
+// collapseByPrefix groups the differences by the first depth segments of their paths, and returns the number of
+// other differences in the group of each first difference, or -1 for the others that are not formatted.
+// Differences at or above the depth are left alone, as well as all of them when the depth is not positive.
+func (d DocDiffs) collapseByPrefix(depth int) map[*Diff]int {
+	collapsed := make(map[*Diff]int)
+	if depth <= 0 {
+		return collapsed
+	}
+	representatives := make(map[string]*Diff)
+	for _, diff := range d {
+		segments := splitPath(diff.Path())
+		if len(segments) <= depth {
+			continue
+		}
+		prefix := fmt.Sprint(segments[:depth])
+		representative, ok := representatives[prefix]
+		if !ok {
+			representatives[prefix] = diff
+			continue
+		}
+		collapsed[representative]++
+		collapsed[diff] = -1
+	}
+	return collapsed
+}
+
+// formatCollapsed formats the difference with the number of other differences it represents,
+// or returns false if it is represented by another difference.
+func formatCollapsed(diff *Diff, collapsed map[*Diff]int, opts FormatOptions) (string, bool) {
+	more := collapsed[diff]
+	if more < 0 {
+		return "", false
+	}
+	line := diff.Format(opts)
+	if more > 0 {
+		line += fmt.Sprintf(" (+%d more under here)", more)
+	}
+	return line, true
+}
+
+// formatGroupedByType formats the differences of each type in separate sections,
+// each one under its own header. Sections without any differences are omitted.
+func (d DocDiffs) formatGroupedByType(collapsed map[*Diff]int, opts FormatOptions) string {
+	sections := []struct {
+		diffType DiffType
+		header   string
+		color    func(string, ...interface{}) string
+	}{
+		{Added, "Added:", hiGreenString},
+		{Deleted, "Deleted:", hiRedString},
+		{Modified, "Modified:", hiYellowString},
+		{Moved, "Moved:", hiBlueString},
+		{Renamed, "Renamed:", hiMagentaString},
+	}
+
+	sectionStrings := make([]string, 0, len(sections))
+	for _, section := range sections {
+		diffsStrings := make([]string, 0, len(d))
+		for _, diff := range d {
+			if diff.Type() != section.diffType {
+				continue
+			}
+			if line, ok := formatCollapsed(diff, collapsed, opts); ok {
+				diffsStrings = append(diffsStrings, line)
+			}
+		}
+		if len(diffsStrings) == 0 {
+			continue
+		}
+
+		header := section.header
+		if !opts.Plain {
+			header = section.color(header)
+		}
+		sectionStrings = append(sectionStrings, header+"\n"+strings.Join(diffsStrings, "\n"))
+	}
+	return strings.Join(sectionStrings, "\n")
 }
 
 type FileDiffs []DocDiffs
 
+// Paths returns the paths of the differences of each document in their order.
+func (d FileDiffs) Paths() [][]string {
+	paths := make([][]string, 0, len(d))
+	for _, docDiffs := range d {
+		paths = append(paths, docDiffs.Paths())
+	}
+	return paths
+}
+
+// Filter returns the differences of the given types in each document, or all of them if no type is given.
+func (d FileDiffs) Filter(types ...DiffType) FileDiffs {
+	filtered := make(FileDiffs, 0, len(d))
+	for _, docDiffs := range d {
+		filtered = append(filtered, docDiffs.Filter(types...))
+	}
+	return filtered
+}
+
+// GroupByType returns the differences of each document grouped by their type.
+func (d FileDiffs) GroupByType() []map[DiffType]DocDiffs {
+	groups := make([]map[DiffType]DocDiffs, 0, len(d))
+	for _, docDiffs := range d {
+		groups = append(groups, docDiffs.GroupByType())
+	}
+	return groups
+}
+
 func (d FileDiffs) Format(opts FormatOptions) string {
-	docDiffsStrings := make([]string, 0, len(d))
+	var b strings.Builder
+	// Writing to a strings.Builder does not fail.
+	_, _ = d.FormatTo(&b, opts)
+	return b.String()
+}
+
+// FormatTo writes the differences to the writer as Format returns them, one document at a time,
+// so the output of all the documents is not held in memory at once. It returns the number of bytes written
+// and the first error that the writer returns, after which nothing more is written.
+func (d FileDiffs) FormatTo(w io.Writer, opts FormatOptions) (int64, error) {
+	var written int64
+	write := func(s string) error {
+		n, err := io.WriteString(w, s)
+		written += int64(n)
+		return err
+	}
+
+	// The differences are transformed up front, so the documents and the total are counted by the ones that are output.
+	if opts.Transform != nil {
+		transformed := make(FileDiffs, len(d))
+		for i, docDiffs := range d {
+			transformed[i] = docDiffs.transform(opts.Transform)
+		}
+		d = transformed
+		opts.Transform = nil
+	}
+
+	// Without any difference, there is nothing to output but the counts.
+	if !d.HasDiff() && !opts.IncludeCounts && !opts.CountsOnly && !opts.GrandTotal {
+		return 0, nil
+	}
+	for _, docDiffs := range d {
+		if opts.ChangedDocsOnly && len(docDiffs) == 0 {
+			continue
+		}
+		// Documents without any output are skipped, so separators only appear between the documents that have differences.
+		docDiffsString := docDiffs.Format(opts)
+		if docDiffsString == "" {
+			continue
+		}
+		if written > 0 {
+			docDiffsString = "\n---\n" + docDiffsString
+		}
+		if err := write(docDiffsString); err != nil {
+			return written, err
+		}
+	}
+	if opts.GrandTotal {
+		// The total is separated by an empty line, so it cannot be mistaken for the counts of the last document.
+		total := "total: " + d.Stat().String()
+		if written > 0 {
+			total = "\n\n" + total
+		}
+		if err := write(total); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// FormatFields returns the paths of the changed fields in the dialect of kubectl, such as ".spec.containers[0].image",
+// one per line without duplicates. The documents that have changed fields are separated by "---".
+func (d FileDiffs) FormatFields() string {
+	if !d.HasDiff() {
+		return ""
+	}
+	docFieldsStrings := make([]string, 0, len(d))
+	for _, docDiffs := range d {
+		fields := make([]string, 0, len(docDiffs))
+		seen := make(map[string]bool)
+		for _, diff := range docDiffs {
+			paths := []string{diff.Path()}
+			// Both the old and the new keys of a rename are changed fields.
+			if newPath, ok := diff.RenamedPath(); ok {
+				paths = append(paths, newPath)
+			}
+			for _, path := range paths {
+				field := pathKubectl(path)
+				if !seen[field] {
+					seen[field] = true
+					fields = append(fields, field)
+				}
+			}
+		}
+		if len(fields) > 0 {
+			docFieldsStrings = append(docFieldsStrings, strings.Join(fields, "\n"))
+		}
+	}
+	return strings.Join(docFieldsStrings, "\n---\n")
+}
+
+// SortByPath sorts the differences of each document by their paths.
+func (d FileDiffs) SortByPath() {
 	for _, docDiffs := range d {
-		docDiffsStrings = append(docDiffsStrings, docDiffs.Format(opts))
+		docDiffs.SortByPath()
 	}
-	return strings.Join(docDiffsStrings, "\n---\n")
 }
 
+// HasDiff reports whether any one of the documents has a difference.
 func (d FileDiffs) HasDiff() bool {
-	return len(d) > 0
+	for _, docDiffs := range d {
+		if len(docDiffs) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // Compare compares two yaml files provided as bytes and returns the differences as FileDiffs,
-// or an error if there's an issue parsing the files.
+// or an error if there's an issue parsing the files, which tells the side that fails, such as "parsing left: ...".
 func Compare(left []byte, right []byte, comments bool, opts DiffOptions) (FileDiffs, error) {
+	result, err := CompareWithResult(left, right, comments, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Diffs, nil
+}
+
+// CompareWithResult is like Compare, but it returns a CompareResult that also holds details about the comparison.
+func CompareWithResult(left []byte, right []byte, comments bool, opts DiffOptions) (*CompareResult, error) {
 	var parserMode parser.Mode
-	if comments {
+	if comments || opts.CompareComments {
 		parserMode |= parser.ParseComments
 	}
 
-	leftAst, err := parser.ParseBytes(left, parserMode)
+	leftAst, err := parseBytes(left, parserMode)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parsing left: %w", err)
 	}
 
-	rightAst, err := parser.ParseBytes(right, parserMode)
+	rightAst, err := parseBytes(right, parserMode)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parsing right: %w", err)
 	}
 
-	return CompareAst(leftAst, rightAst, opts), nil
+	return CompareAstWithResult(leftAst, rightAst, opts)
 }
 
 // CompareFile compares two yaml files specified by file paths and returns the differences as FileDiffs,
-// or an error if there's an issue reading or parsing the files.
+// or an error if there's an issue reading or parsing the files. The error of reading a file is returned as it is,
+// so it can be checked by os.IsNotExist, while the error of parsing one tells the side that fails, such as "parsing right: ...".
 func CompareFile(leftFile string, rightFile string, comments bool, opts DiffOptions) (FileDiffs, error) {
+	result, err := CompareFileWithResult(leftFile, rightFile, comments, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.Diffs, nil
+}
+
+// CompareFileWithResult is like CompareFile, but it returns a CompareResult that also holds details about the comparison.
+func CompareFileWithResult(leftFile string, rightFile string, comments bool, opts DiffOptions) (*CompareResult, error) {
 	var parserMode parser.Mode
-	if comments {
+	if comments || opts.CompareComments {
 		parserMode |= parser.ParseComments
 	}
 
-	leftAst, err := parser.ParseFile(leftFile, parserMode)
+	leftAst, err := parseFile(leftFile, "left", parserMode)
+	if err != nil {
+		return nil, err
+	}
+
+	rightAst, err := parseFile(rightFile, "right", parserMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return CompareAstWithResult(leftAst, rightAst, opts)
+}
+
+// ErrInvalidUTF8 is returned, wrapped with the side it occurs on, when an input is not valid UTF-8 text, such as a binary file.
+var ErrInvalidUTF8 = errors.New("input is not valid UTF-8 text")
+
+// parseBytes parses the yaml file, or returns ErrInvalidUTF8 without parsing it if it is not valid UTF-8 text,
+// as the parser fails on such input with errors that do not tell the cause.
+func parseBytes(b []byte, mode parser.Mode) (*ast.File, error) {
+	if !utf8.Valid(b) {
+		return nil, ErrInvalidUTF8
+	}
+	return parser.ParseBytes(b, mode)
+}
+
+// parseFile reads and parses the yaml file at the path as parseBytes does, where the error of parsing it is wrapped
+// with the side of the file, such as "parsing left: ...", and the error of reading it is returned as it is.
+func parseFile(path, side string, mode parser.Mode) (*ast.File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parseBytes(b, mode)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", side, err)
+	}
+	f.Name = path
+	return f, nil
+}
+
+// CompareFileToBytes compares the yaml file specified by the file path with the yaml file provided as bytes.
+// The error of reading the file is returned as it is, so it can be checked by os.IsNotExist.
+func CompareFileToBytes(leftFile string, right []byte, comments bool, opts DiffOptions) (FileDiffs, error) {
+	left, err := os.ReadFile(leftFile)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(left, right, comments, opts)
+}
+
+// CompareBytesToFile compares the yaml file provided as bytes with the yaml file specified by the file path.
+// The error of reading the file is returned as it is, so it can be checked by os.IsNotExist.
+func CompareBytesToFile(left []byte, rightFile string, comments bool, opts DiffOptions) (FileDiffs, error) {
+	right, err := os.ReadFile(rightFile)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(left, right, comments, opts)
+}
+
+// CompareReader compares the yaml files read from the readers, such as request bodies or archive entries.
+// The error of reading either of them is returned as it is, so it can be checked by errors.Is.
+func CompareReader(left io.Reader, right io.Reader, comments bool, opts DiffOptions) (FileDiffs, error) {
+	leftBytes, err := io.ReadAll(left)
+	if err != nil {
+		return nil, err
+	}
+	rightBytes, err := io.ReadAll(right)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(leftBytes, rightBytes, comments, opts)
+}
+
+// CompareValues compares two values, such as the maps or slices decoded by yaml.Unmarshal, as single yaml documents.
+// The values are encoded by the yaml encoder first, which sorts the keys of maps, so the paths of the differences
+// are the same as the ones of the yaml files holding them, while their lines refer to the encoded documents.
+func CompareValues(left, right any, opts DiffOptions) (FileDiffs, error) {
+	leftBytes, err := yaml.Marshal(left)
+	if err != nil {
+		return nil, fmt.Errorf("encoding left: %w", err)
+	}
+	rightBytes, err := yaml.Marshal(right)
+	if err != nil {
+		return nil, fmt.Errorf("encoding right: %w", err)
+	}
+	return Compare(leftBytes, rightBytes, false, opts)
+}
+
+// CompareAst compares two yaml documents represented as ASTs and returns the differences as FileDiffs,
+// or an error if the documents cannot be compared with the given options.
+func CompareAst(left *ast.File, right *ast.File, opts DiffOptions) (FileDiffs, error) {
+	result, err := CompareAstWithResult(left, right, opts)
 	if err != nil {
 		return nil, err
 	}
+	return result.Diffs, nil
+}
 
-	rightAst, err := parser.ParseFile(rightFile, parserMode)
+// CompareDocument compares only the documents at the index of two yaml files represented as ASTs, counting from zero,
+// and returns their differences. A document that exists on one side only is compared against an empty document.
+// It returns an error if neither of the files has a document at the index.
+func CompareDocument(left *ast.File, right *ast.File, index int, opts DiffOptions) (DocDiffs, error) {
+	leftDocs := documents(left)
+	rightDocs := documents(right)
+	if index < 0 || (index >= len(leftDocs) && index >= len(rightDocs)) {
+		return nil, fmt.Errorf("document index %d is out of range, the files have %d and %d documents", index, len(leftDocs), len(rightDocs))
+	}
+	diffs, err := CompareAst(documentFile(left, leftDocs, index), documentFile(right, rightDocs, index), opts)
 	if err != nil {
 		return nil, err
 	}
+	return diffs[0], nil
+}
 
-	return CompareAst(leftAst, rightAst, opts), nil
+// documentFile returns a file that holds only the document at the index, or no document if there is none.
+func documentFile(f *ast.File, docs []*ast.DocumentNode, index int) *ast.File {
+	if index >= len(docs) {
+		return &ast.File{Name: f.Name}
+	}
+	return &ast.File{Name: f.Name, Docs: docs[index : index+1]}
 }
 
-// CompareAst compares two yaml documents represented as ASTs and returns the differences as FileDiffs.
-func CompareAst(left *ast.File, right *ast.File, opts DiffOptions) FileDiffs {
-	var docDiffs = make(FileDiffs, max(len(left.Docs), len(left.Docs)))
-	for i := 0; i < len(docDiffs); i++ {
-		var l, r *ast.DocumentNode
-		if len(left.Docs) > i {
-			l = left.Docs[i]
+// CompareAstWithResult is like CompareAst, but it returns a CompareResult that also holds details about the comparison.
+func CompareAstWithResult(left *ast.File, right *ast.File, opts DiffOptions) (*CompareResult, error) {
+	if opts.Canonicalize {
+		var err error
+		if left, err = canonicalizeFile(left); err != nil {
+			return nil, err
 		}
-		if len(right.Docs) > i {
-			r = right.Docs[i]
+		if right, err = canonicalizeFile(right); err != nil {
+			return nil, err
 		}
-		docDiff := DocDiffs(compareNodes(l.Body, r.Body, opts))
-		sort.Sort(docDiff)
+	}
+
+	filter, err := newPathFilter(opts.IncludePaths, opts.ExcludePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	leftDocs := documents(left)
+	rightDocs := documents(right)
+	leftShared := sharedAnchors(leftDocs, opts)
+	rightShared := sharedAnchors(rightDocs, opts)
+	var docDiffs = make(FileDiffs, max(len(leftDocs), len(rightDocs)))
+	comparators := make([]*comparator, len(docDiffs))
+	compareDocument := func(i int) {
+		// A document that exists only on one side is compared against a missing body, so it is added or deleted as a whole,
+		// unless it is empty itself.
+		var l, r ast.Node
+		if len(leftDocs) > i {
+			l = documentBody(leftDocs[i])
+		}
+		if len(rightDocs) > i {
+			r = documentBody(rightDocs[i])
+		}
+		c := newComparator(opts)
+		c.resolveAnchors(l, r, leftShared, rightShared)
+		docDiff := filter.filter(c.compareNodes(l, r))
+		docDiff.sort(opts.StableOrder)
 		docDiffs[i] = docDiff
+		comparators[i] = c
+	}
+
+	jobs := opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	if jobs == 1 || len(docDiffs) < 2 {
+		for i := range docDiffs {
+			compareDocument(i)
+		}
+	} else {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, jobs)
+		for i := range docDiffs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				compareDocument(i)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	// The state of the documents is merged in their order, so the result does not depend on the number of jobs.
+	c := newComparator(opts)
+	for _, other := range comparators {
+		c.merge(other)
+		if c.err != nil {
+			return nil, c.err
+		}
+	}
+	return &CompareResult{
+		Diffs:              docDiffs,
+		ReorderedSequences: c.reorderedSequences,
+		Warnings:           c.warnings,
+		LeftAST:            left,
+		RightAST:           right,
+	}, nil
+}
+
+// CompareNodes compares two nodes of yaml ASTs, such as subtrees extracted from documents, and returns their differences
+// sorted by the lines of the nodes, or an error if the nodes cannot be compared with the given options.
+// The paths of the differences are the paths of the nodes in their documents.
+func CompareNodes(left ast.Node, right ast.Node, opts DiffOptions) (DocDiffs, error) {
+	filter, err := newPathFilter(opts.IncludePaths, opts.ExcludePaths)
+	if err != nil {
+		return nil, err
+	}
+	c := newComparator(opts)
+	c.resolveAnchors(left, right, nil, nil)
+	diffs := filter.filter(c.compareNodes(left, right))
+	if c.err != nil {
+		return nil, c.err
 	}
-	return docDiffs
+	diffs.sort(opts.StableOrder)
+	return diffs, nil
+}
+
+// CompareResult holds the differences found by a comparison together with details about how they were found.
+type CompareResult struct {
+	// Diffs holds the differences of each document.
+	Diffs FileDiffs
+
+	// ReorderedSequences is the number of sequences whose items appear in a different order on each side.
+	// It is only counted when IgnoreSeqOrder or MatchByKeys is set, as such order-only changes are not reported as differences then.
+	ReorderedSequences int
+
+	// Warnings are the diagnostics that do not prevent the comparison, such as duplicate keys in a mapping.
+	Warnings []string
+
+	// LeftAST and RightAST are the compared files, which the nodes of the differences belong to.
+	// They are the files that are given to CompareAstWithResult, or the ones that are parsed by the other functions,
+	// unless Canonicalize is set, in which case they are the canonical files.
+	// They are shared with the differences rather than copied, so they must not be modified while the differences are in use.
+	LeftAST  *ast.File
+	RightAST *ast.File
 }
 
 // DiffOptions specifies options for customizing the behavior of the comparison.
@@ -215,10 +1055,181 @@ type DiffOptions struct {
 	// IgnoreSeqOrder, when true, treats arrays as equal regardless of the order of their items.
 	// For instance, the arrays [1, 2] and [2, 1] will be considered equal.
 	IgnoreSeqOrder bool
+
+	// SequenceLCS, when true, aligns the items of sequences by the longest common subsequence of their equal items
+	// instead of their indices, so inserting or deleting an item reports only that item, such as "+ items[0]: new",
+	// rather than every item after it. The items between the aligned ones are compared in their order.
+	// It takes time proportional to the product of the lengths of the sequences, apart from their common prefix and suffix.
+	// IgnoreSeqOrder, MatchByKeys and KeyedSequences take precedence over it.
+	SequenceLCS bool
+
+	// CoerceStringNumbers, when true, treats a string holding a plain numeric literal as equal to a number of the same value.
+	// For instance, "8080" and 8080 will be considered equal, whereas "08" and 8 will not.
+	CoerceStringNumbers bool
+
+	// CoerceScalarTypes, when true, treats a string as equal to a number, boolean or null whose canonical representation it holds,
+	// such as "42" and 42, "1.5" and 1.50, "true" and true, or "null" and null, where the canonical representation of a float
+	// is the one that the yaml encoder writes, such as "1.0" for 1.0. Unlike CoerceStringNumbers, it also covers booleans and nulls.
+	// Other notations of the values are not canonical, so "08" and 8.0, "1e0" and 1, or "yes" and true, are still different.
+	CoerceScalarTypes bool
+
+	// IntersectionOnly, when true, compares only the mapping keys that exist on both sides,
+	// so keys that are present in just one of the documents are not reported.
+	IntersectionOnly bool
+
+	// TreatNullAsAbsent, when true, considers a mapping key whose value is null, such as "a: null", "a: ~" or "a:",
+	// equal to the key being missing on the other side, so it is reported neither as added nor as deleted.
+	// A null value is still different from a non-null value of the same key.
+	TreatNullAsAbsent bool
+
+	// IgnoreKeyCase, when true, matches mapping keys regardless of their case.
+	// For instance, the keys Name and name will be considered the same key.
+	// Comparing a mapping that has distinct keys differing only by case, such as Name and NAME, results in an error.
+	IgnoreKeyCase bool
+
+	// IgnoreValueCase, when true, considers the string values equal regardless of their case, such as "Enabled" and "enabled".
+	// It does not apply to booleans or numbers, nor to keys, which IgnoreKeyCase matches regardless of their case.
+	IgnoreValueCase bool
+
+	// IgnoreScalarWhitespace, when true, considers the string values equal if they only differ by their leading
+	// and trailing white space, such as "alice " and "alice". A block scalar is compared as a whole,
+	// so the white space within it, such as its indentation or the spaces at the end of its lines, is still compared.
+	// Unlike TrimNormalizer, it compares only strings with strings, so " 1" and 1 are still different.
+	IgnoreScalarWhitespace bool
+
+	// NormalizeNewlines, when true, considers the CRLF line endings within block scalars, such as the ones of files
+	// checked out on Windows, equal to LF line endings. Other white space is still compared,
+	// and the values are rendered as they are written in the documents.
+	NormalizeNewlines bool
+
+	// IgnoreIndices maps the paths of sequences to the indices of their items that are excluded from the comparison.
+	// For instance, {"items": {0}} ignores the first item of the items sequence, whatever it holds on either side.
+	// The root path is denoted by "$".
+	IgnoreIndices map[string][]int
+
+	// IncludePaths, when set, keeps only the differences at or under the paths that match one of the patterns,
+	// such as "spec" or "spec.containers[*].image". A key of a pattern may hold the wildcards of path.Match,
+	// and "*" or "[*]" matches any key or index.
+	IncludePaths []string
+
+	// ExcludePaths leaves out the differences at or under the paths that match one of the patterns, such as "metadata.annotations".
+	// The patterns are written as in IncludePaths, and they win over IncludePaths when both match a difference.
+	ExcludePaths []string
+
+	// Normalizers are applied in order to the values of scalars before they are compared,
+	// so values that are equal after normalization are not reported, even if their types differ.
+	// For instance, with BooleanNormalizer, the string "yes" and the boolean true will be considered equal.
+	Normalizers []ScalarNormalizer
+
+	// MatchByKeys are the keys that identify the items of sequences of mappings, such as "name".
+	// When every item of both sequences holds a unique value for one of the keys, the items are matched by the value of the first such key,
+	// regardless of their order, so an inserted or reordered item does not cause differences in the items after it.
+	// For instance, K8sListKeys identifies the items of the common Kubernetes lists.
+	MatchByKeys []string
+
+	// KeyedSequences maps the paths of sequences of mappings to the keys that identify their items, such as {"spec.containers": "name"},
+	// so the items under the path are matched by the value of the key regardless of their order, as with MatchByKeys.
+	// A path may hold the wildcards of IncludePaths, such as "spec.containers[*].env", and the keys take precedence over MatchByKeys.
+	// When an item lacks the key, or its value is not unique, the sequence is compared as if it had no key.
+	KeyedSequences map[string]string
+
+	// DetectMoves, when true, reports the items of sequences that are matched regardless of their order,
+	// as with IgnoreSeqOrder, MatchByKeys or KeyedSequences, but appear in a different relative order on the right side as Moved,
+	// such as "↕ items[0→3]: value". The items that are only shifted by insertions or deletions are not reported,
+	// and the fewest items are reported so that the order of the rest is kept.
+	DetectMoves bool
+
+	// DetectRenames, when true, reports a key of a mapping that exists only on the left side and another key of the same mapping
+	// that exists only on the right side as Renamed if their values are equal, such as "⟳ oldName → newName: x",
+	// instead of a deletion and an addition. Each key on the left side is paired with the first such key on the right side.
+	DetectRenames bool
+
+	// MaxDepth, when set, collapses the differences within a mapping or a sequence at the given depth into a single modification
+	// of the collection, so a replaced subtree is reported once instead of by each of its leaves. The depth of a path is
+	// the number of its keys and indices, such as 2 for spec.containers, and 0 collapses the differences of a document into one.
+	// The differences are collapsed before IncludePaths and ExcludePaths are matched against their paths.
+	MaxDepth *int
+
+	// ResolveMergeKeys, when true, compares the effective keys of the mappings that use merge keys, such as "<<: *defaults",
+	// instead of comparing "<<" as an ordinary key, so a mapping that merges its values and one that inlines them are equal.
+	// The keys that are defined explicitly override the merged ones. Differences in the merged values are reported
+	// at the paths where the merged mappings are defined, and anchors are otherwise ignored.
+	ResolveMergeKeys bool
+
+	// ExpandAliases, when true, compares the aliases, such as "*defaults", as the values of the anchors they refer to,
+	// so an aliased value and the same value written inline are equal. Differences in the aliased values are reported
+	// at the paths where the anchors are defined. An alias within the value of its own anchor results in an error.
+	ExpandAliases bool
+
+	// SharedAnchors, when true, makes the anchors of the first document available to the aliases and merge keys
+	// of the later documents, such as a document of defaults that the others refer to, whose aliases would otherwise
+	// be compared as they are written, as an anchor belongs to its own document. An anchor that a later document defines
+	// takes precedence over the one of the first document with the same name. It applies with ResolveMergeKeys or ExpandAliases.
+	SharedAnchors bool
+
+	// StrictTypes, when true, stops the comparison with an error as soon as a value changes its type,
+	// such as a mapping that becomes a scalar or an integer that becomes a string, instead of reporting it as a modification.
+	// Plain and block strings are considered the same type.
+	StrictTypes bool
+
+	// Canonicalize, when true, re-serializes the documents through the yaml encoder before they are compared,
+	// which sorts the keys, resolves anchors, aliases and merge keys, and writes the scalars in their canonical form,
+	// so only semantic differences remain. The lines in the differences then refer to the canonical documents.
+	// It decodes and encodes every document once more, so it costs considerably more time and memory than the comparison itself.
+	Canonicalize bool
+
+	// DetectKeyOrder, when true, reports the mappings that have the same keys with equal values in a different order,
+	// such as ordered pipeline steps, which are rendered as "~ steps: (build, test) -> (test, build)".
+	// A mapping with any other difference within it is reported by that difference alone.
+	DetectKeyOrder bool
+
+	// CompareComments, when true, reports the nodes whose comments differ even though their values are equal,
+	// such as "a: 1 # old" and "a: 1 # new", which are rendered as "~ a: # old -> # new".
+	// The documents are then parsed with their comments, so the comments are also included in the values of other differences.
+	CompareComments bool
+
+	// StableOrder, when true, orders the differences at the same position by their paths,
+	// which otherwise keep the order they are found in. It does not change the order of the differences at different positions.
+	StableOrder bool
+
+	// Jobs is the maximum number of documents that are compared in parallel.
+	// When it is zero or negative, GOMAXPROCS is used, and 1 compares the documents sequentially.
+	Jobs int
 }
 
+// K8sListKeys are the keys that identify the items of the common Kubernetes lists,
+// such as containers, environment variables, volumes, ports and volume mounts, to be used as MatchByKeys.
+var K8sListKeys = []string{"name", "containerPort", "mountPath", "key"}
+
 var DefaultDiffOptions = DiffOptions{
-	IgnoreSeqOrder: false,
+	IgnoreSeqOrder:         false,
+	SequenceLCS:            false,
+	CoerceStringNumbers:    false,
+	CoerceScalarTypes:      false,
+	IntersectionOnly:       false,
+	TreatNullAsAbsent:      false,
+	IgnoreKeyCase:          false,
+	IgnoreValueCase:        false,
+	IgnoreScalarWhitespace: false,
+	NormalizeNewlines:      false,
+	IgnoreIndices:          nil,
+	IncludePaths:           nil,
+	ExcludePaths:           nil,
+	Normalizers:            nil,
+	MatchByKeys:            nil,
+	KeyedSequences:         nil,
+	DetectMoves:            false,
+	DetectRenames:          false,
+	MaxDepth:               nil,
+	ResolveMergeKeys:       false,
+	ExpandAliases:          false,
+	SharedAnchors:          false,
+	StrictTypes:            false,
+	Canonicalize:           false,
+	DetectKeyOrder:         false,
+	CompareComments:        false,
+	StableOrder:            false,
+	Jobs:                   0,
 }
 
 // FormatOptions specifies options for formatting the output of the comparison.
@@ -226,15 +1237,100 @@ type FormatOptions struct {
 	// Plain disables colored output when set to true.
 	Plain bool
 
-	// Silent suppresses the display of values when set to true.
-	Silent bool
+	// PathsOnly suppresses the display of values when set to true, leaving only the sign and path of each difference.
+	PathsOnly bool
 
 	// Metadata includes additional metadata, such as line numbers or types, when set to true.
 	Metadata bool
+
+	// ExtendedMetadata adds the number of leaves and the depth of the added and deleted collections to their metadata,
+	// such as [line:14 <Mapping> leaves:8 depth:3], when set to true together with Metadata.
+	ExtendedMetadata bool
+
+	// CollectionSizes prepends the number of items or keys to the values of the added and deleted collections,
+	// such as "(3 items)" or "(2 keys)", when set to true.
+	CollectionSizes bool
+
+	// GroupByType lists the additions, deletions and modifications in separate sections when set to true.
+	GroupByType bool
+
+	// PreserveQuotes renders string values exactly as they are written in the source,
+	// keeping their original quoting style and escapes, when set to true.
+	PreserveQuotes bool
+
+	// EmptyPlaceholder, when set, is rendered in place of null and empty string values, such as "<empty>".
+	EmptyPlaceholder string
+
+	// ASCIISymbols renders the moved items and the renamed keys with the ASCII signs "^" and "=" and the arrow "->",
+	// such as "^ items[0->3]: x", instead of "↕", "⟳" and "→", when set to true.
+	ASCIISymbols bool
+
+	// MaxValueLines, when positive, truncates the rendered values that span more lines, such as collections and block scalars,
+	// to the given number of lines, noting the number of the others after them, such as "... (+12 more lines)".
+	MaxValueLines int
+
+	// PathStyle is the notation of the rendered paths, which is PathStyleDot by default.
+	PathStyle PathStyle
+
+	// IncludeCounts prepends the number of added, deleted and modified differences to the output of each document when set to true.
+	IncludeCounts bool
+
+	// CountsOnly renders only the number of added, deleted and modified differences of each document when set to true,
+	// such as "1 added, 0 deleted, 2 modified", leaving out the differences themselves.
+	CountsOnly bool
+
+	// Context, when positive, limits the output of FormatUnified to the hunks of the changed lines with the given number
+	// of unchanged lines around them, each one under a header such as "@@ -3,7 +3,8 @@", instead of listing every line.
+	Context int
+
+	// Transform, when set, is called with each difference right before it is formatted.
+	// It returns the difference to format in its place, which may be rewritten, for instance via WithPath,
+	// or false to drop the difference from the output.
+	Transform func(*Diff) (*Diff, bool)
+
+	// CollapseDepth, when positive, shows only the first of the differences whose paths share the same prefix
+	// of the given number of segments, noting the number of the others after it, such as "(+3 more under here)".
+	// Unlike omitting values, it reduces the volume of noisy outputs while keeping the changed parts of the structure visible.
+	CollapseDepth int
+
+	// ChangedDocsOnly omits the documents without differences from the output entirely when set to true,
+	// even when other options, such as IncludeCounts, render something for them.
+	ChangedDocsOnly bool
+
+	// GrandTotal appends the number of differences across all documents to the output when set to true,
+	// separated from the differences by an empty line.
+	GrandTotal bool
 }
 
+// PathStyle is the notation that paths are rendered in.
+type PathStyle string
+
+const (
+	// PathStyleDot renders paths with dots and brackets, such as "spec.containers[0].image".
+	PathStyleDot PathStyle = "dot"
+	// PathStylePointer renders paths as JSON Pointers, such as "/spec/containers/0/image".
+	PathStylePointer PathStyle = "pointer"
+	// PathStyleKubectl renders paths as kubectl field paths, such as ".spec.containers[0].image".
+	PathStyleKubectl PathStyle = "kubectl"
+)
+
 var DefaultOutputOptions = FormatOptions{
-	Plain:    false,
-	Silent:   false,
-	Metadata: false,
+	Plain:            false,
+	PathsOnly:        false,
+	Metadata:         false,
+	ExtendedMetadata: false,
+	CollectionSizes:  false,
+	GroupByType:      false,
+	PreserveQuotes:   false,
+	EmptyPlaceholder: "",
+	ASCIISymbols:     false,
+	MaxValueLines:    0,
+	PathStyle:        PathStyleDot,
+	IncludeCounts:    false,
+	CountsOnly:       false,
+	GrandTotal:       false,
+	ChangedDocsOnly:  false,
+	Transform:        nil,
+	CollapseDepth:    0,
+	Context:          0,
 }
@@ -1,13 +1,18 @@
 package compare
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/goccy/go-yaml"
 	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +47,89 @@ var diffValues = [][2]string{
 	},
 }
 
+func TestDiffTypeString(t *testing.T) {
+	tests := []struct {
+		diffType DiffType
+		expected string
+	}{
+		{Added, "added"},
+		{Deleted, "deleted"},
+		{Modified, "modified"},
+		{Moved, "moved"},
+		{Renamed, "renamed"},
+		{DiffType(-1), "unknown"},
+		{DiffType(5), "unknown"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.diffType.String())
+		assert.Equal(t, test.expected, fmt.Sprint(test.diffType))
+	}
+}
+
+func TestStat(t *testing.T) {
+	left := []byte("a: 1\nb: 2\nc: [x, y]\n---\nd: 1\n")
+	right := []byte("a: 2\nc: [x, z, w]\ne: 3\n---\nd: 1\nf: 4\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, DiffCount{Added: 2, Deleted: 1, Modified: 2}, diffs[0].Stat())
+	assert.Equal(t, DiffCount{Added: 1}, diffs[1].Stat())
+	assert.Equal(t, DiffCount{Added: 3, Deleted: 1, Modified: 2}, diffs.Stat())
+	assert.Equal(t, 6, diffs.Stat().Total())
+	assert.Equal(t, DiffCount{}, FileDiffs{}.Stat())
+}
+
+func TestFilter(t *testing.T) {
+	left := []byte("a: 1\nb: 2\n---\nc: 3\n")
+	right := []byte("a: 2\nd: 4\n---\nc: 3\ne: 5\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	original := make(FileDiffs, len(diffs))
+	for i, docDiffs := range diffs {
+		original[i] = append(DocDiffs(nil), docDiffs...)
+	}
+
+	added := diffs.Filter(Added)
+	assert.Len(t, added, 2)
+	assert.Equal(t, DocDiffs{diffs[0][2]}, added[0])
+	assert.Equal(t, DocDiffs{diffs[1][0]}, added[1])
+
+	changed := diffs[0].Filter(Deleted, Modified)
+	assert.Equal(t, DocDiffs{diffs[0][0], diffs[0][1]}, changed)
+
+	assert.Equal(t, diffs, diffs.Filter())
+	assert.Empty(t, diffs[1].Filter(Deleted))
+	assert.Equal(t, original, diffs)
+}
+
+func TestGroupByType(t *testing.T) {
+	left := []byte("a: 1\nb: 2\nc: 3\nd: 4\n---\ne: 5\n")
+	right := []byte("a: 2\nc: 4\nd: 4\nf: 6\ng: 7\n---\ne: 5\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	groups := diffs[0].GroupByType()
+	assert.Len(t, groups, 3)
+	assert.Equal(t, []string{"a", "c"}, groups[Modified].Paths())
+	assert.Equal(t, []string{"b"}, groups[Deleted].Paths())
+	assert.Equal(t, []string{"f", "g"}, groups[Added].Paths())
+	stat := diffs[0].Stat()
+	assert.Len(t, groups[Added], stat.Added)
+	assert.Len(t, groups[Deleted], stat.Deleted)
+	assert.Len(t, groups[Modified], stat.Modified)
+
+	empty := DocDiffs{}.GroupByType()
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+
+	fileGroups := diffs.GroupByType()
+	assert.Len(t, fileGroups, 2)
+	assert.Equal(t, groups, fileGroups[0])
+	assert.Empty(t, fileGroups[1])
+}
+
 func TestCompareFile(t *testing.T) {
 	diffs, err := CompareFile(fileLeft, fileRight, false, DefaultDiffOptions)
 	assert.NoError(t, err)
@@ -54,6 +142,58 @@ func TestCompareFile(t *testing.T) {
 	}
 }
 
+func TestCompareParseError(t *testing.T) {
+	invalid := []byte("a: {b: 1\n")
+	valid := []byte("a: 1\n")
+
+	_, err := Compare(invalid, valid, false, DefaultDiffOptions)
+	assert.ErrorContains(t, err, "parsing left: ")
+	_, err = Compare(valid, invalid, false, DefaultDiffOptions)
+	assert.ErrorContains(t, err, "parsing right: ")
+
+	invalidFile := filepath.Join(t.TempDir(), "invalid.yaml")
+	assert.NoError(t, os.WriteFile(invalidFile, invalid, 0o644))
+	_, err = CompareFile(invalidFile, fileRight, false, DefaultDiffOptions)
+	assert.ErrorContains(t, err, "parsing left: ")
+	_, err = CompareFile(fileLeft, invalidFile, false, DefaultDiffOptions)
+	assert.ErrorContains(t, err, "parsing right: ")
+
+	// The errors of reading the files are not parsing errors, so they are returned as they are.
+	missingFile := filepath.Join(t.TempDir(), "missing.yaml")
+	_, err = CompareFile(missingFile, fileRight, false, DefaultDiffOptions)
+	assert.True(t, os.IsNotExist(err))
+	assert.NotContains(t, err.Error(), "parsing")
+	_, err = CompareFile(fileLeft, missingFile, false, DefaultDiffOptions)
+	assert.True(t, os.IsNotExist(err))
+	assert.NotContains(t, err.Error(), "parsing")
+}
+
+func TestCompareInvalidUTF8(t *testing.T) {
+	binary := []byte{0xff, 0xfe}
+	valid := []byte("a: 1\n")
+
+	_, err := Compare(binary, valid, false, DefaultDiffOptions)
+	assert.ErrorIs(t, err, ErrInvalidUTF8)
+	assert.EqualError(t, err, "parsing left: input is not valid UTF-8 text")
+	_, err = Compare(valid, binary, false, DefaultDiffOptions)
+	assert.ErrorIs(t, err, ErrInvalidUTF8)
+	assert.EqualError(t, err, "parsing right: input is not valid UTF-8 text")
+
+	binaryFile := filepath.Join(t.TempDir(), "binary.yaml")
+	assert.NoError(t, os.WriteFile(binaryFile, binary, 0o644))
+	_, err = CompareFile(binaryFile, fileRight, false, DefaultDiffOptions)
+	assert.ErrorIs(t, err, ErrInvalidUTF8)
+	assert.ErrorContains(t, err, "parsing left: ")
+	_, err = CompareFile(fileLeft, binaryFile, false, DefaultDiffOptions)
+	assert.ErrorIs(t, err, ErrInvalidUTF8)
+	assert.ErrorContains(t, err, "parsing right: ")
+
+	err = CompareStream(bytes.NewReader(valid), bytes.NewReader(binary), false, DefaultDiffOptions, func(int, DocDiffs) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrInvalidUTF8)
+}
+
 func TestCompare(t *testing.T) {
 	diffs, err := Compare(readFile(t, fileLeft), readFile(t, fileRight), false, DefaultDiffOptions)
 	assert.NoError(t, err)
@@ -66,10 +206,268 @@ func TestCompare(t *testing.T) {
 	}
 }
 
+func TestCompareFileToBytes(t *testing.T) {
+	diffs, err := CompareFileToBytes(fileLeft, readFile(t, fileRight), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Len(t, diffs[0], 5)
+
+	for i, diff := range diffs[0] {
+		assert.Equal(t, diff.leftNode.GetToken().Value, diffValues[i][0])
+		assert.Equal(t, diff.rightNode.GetToken().Value, diffValues[i][1])
+	}
+
+	_, err = CompareFileToBytes("testdata/missing.yaml", readFile(t, fileRight), false, DefaultDiffOptions)
+	assert.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCompareBytesToFile(t *testing.T) {
+	diffs, err := CompareBytesToFile(readFile(t, fileLeft), fileRight, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Len(t, diffs[0], 5)
+
+	for i, diff := range diffs[0] {
+		assert.Equal(t, diff.leftNode.GetToken().Value, diffValues[i][0])
+		assert.Equal(t, diff.rightNode.GetToken().Value, diffValues[i][1])
+	}
+
+	_, err = CompareBytesToFile(readFile(t, fileLeft), "testdata/missing.yaml", false, DefaultDiffOptions)
+	assert.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestCompareDocumentCount(t *testing.T) {
+	one := []byte("a: 1\n")
+	three := []byte("a: 1\n---\nb: 2\n---\nc: 3\n")
+
+	diffs, err := Compare(one, three, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 3)
+	assert.Empty(t, diffs[0])
+	for _, docDiffs := range diffs[1:] {
+		assert.Len(t, docDiffs, 1)
+		assert.Equal(t, Added, docDiffs[0].Type())
+		assert.Equal(t, "$", docDiffs[0].Path())
+	}
+	assert.Equal(t, "+ $: \n  b: 2\n---\n+ $: \n  c: 3", diffs.Format(FormatOptions{Plain: true}))
+
+	diffs, err = Compare(three, one, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 3)
+	assert.Equal(t, DiffCount{Deleted: 2}, diffs.Stat())
+}
+
+func TestCompareEmptyDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		expected []int
+	}{
+		{name: "empty file and empty documents", left: "", right: "---\n---\n---\n", expected: []int{0, 0, 0}},
+		{name: "empty documents", left: "---\n---\n", right: "---\n---\n", expected: []int{0, 0}},
+		{name: "empty document between documents", left: "a: 1\n---\n---\nb: 2\n", right: "a: 1\n---\n---\nb: 3\n", expected: []int{0, 0, 1}},
+		{name: "empty document and document", left: "a: 1\n---\n---\nb: 2\n", right: "a: 1\n---\nc: 1\n---\nb: 2\n", expected: []int{0, 1, 0}},
+		{name: "trailing empty document", left: "a: 1\n---\n", right: "a: 1\n", expected: []int{0, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs, err := Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+			assert.NoError(t, err)
+			counts := make([]int, 0, len(diffs))
+			for _, docDiffs := range diffs {
+				counts = append(counts, len(docDiffs))
+			}
+			assert.Equal(t, test.expected, counts)
+		})
+	}
+
+	diffs, err := Compare([]byte("a: 1\n---\n---\nb: 2\n"), []byte("a: 1\n---\nc: 1\n---\nb: 2\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "+ $: \n  c: 1", diffs.Format(FormatOptions{Plain: true}))
+}
+
+func TestCompareStableOrder(t *testing.T) {
+	left := readFile(t, fileLeft)
+	right := readFile(t, fileRight)
+
+	expected, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	opts := DefaultDiffOptions
+	opts.StableOrder = true
+	diffs, err := Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, diffs)
+
+	t.Run("same position", func(t *testing.T) {
+		file, err := parser.ParseBytes([]byte("a: 1\nb: 2\n"), 0)
+		assert.NoError(t, err)
+		values := file.Docs[0].Body.(*ast.MappingNode).Values
+		first := &Diff{leftNode: values[0].Value, rightNode: values[1].Value}
+		second := &Diff{leftNode: values[1].Value, rightNode: values[0].Value}
+
+		diffs := DocDiffs{second, first.WithPath("z"), first.WithPath("m[10]"), first.WithPath("m[2]")}
+		diffs.sort(false)
+		assert.Equal(t, []string{"z", "m[10]", "m[2]", "b"}, diffs.Paths())
+
+		diffs = DocDiffs{second, first.WithPath("z"), first.WithPath("m[10]"), first.WithPath("m[2]")}
+		diffs.sort(true)
+		assert.Equal(t, []string{"m[2]", "m[10]", "z", "b"}, diffs.Paths())
+	})
+}
+
+func TestCompareResultAST(t *testing.T) {
+	left := []byte("a: 1\nb: [x, y]\n")
+	right := []byte("a: 2\nb: [x, y]\n")
+
+	result, err := CompareWithResult(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.NotNil(t, result.LeftAST)
+	assert.NotNil(t, result.RightAST)
+	assert.Len(t, result.LeftAST.Docs, 1)
+	assert.Len(t, result.RightAST.Docs, 1)
+	assert.Same(t, result.LeftAST.Docs[0].Body.(*ast.MappingNode).Values[0].Value, result.Diffs[0][0].leftNode)
+
+	leftAst, err := parser.ParseBytes(left, 0)
+	assert.NoError(t, err)
+	rightAst, err := parser.ParseBytes(right, 0)
+	assert.NoError(t, err)
+	result, err = CompareAstWithResult(leftAst, rightAst, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Same(t, leftAst, result.LeftAST)
+	assert.Same(t, rightAst, result.RightAST)
+}
+
+func TestCompareReader(t *testing.T) {
+	diffs, err := CompareReader(bytes.NewReader(readFile(t, fileLeft)), strings.NewReader(string(readFile(t, fileRight))), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Len(t, diffs[0], 5)
+
+	for i, diff := range diffs[0] {
+		assert.Equal(t, diff.leftNode.GetToken().Value, diffValues[i][0])
+		assert.Equal(t, diff.rightNode.GetToken().Value, diffValues[i][1])
+	}
+
+	expected, err := Compare([]byte{}, []byte{}, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	diffs, err = CompareReader(strings.NewReader(""), strings.NewReader(""), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, diffs)
+
+	_, err = CompareReader(strings.NewReader("a: 1"), failingReader{io.ErrUnexpectedEOF}, false, DefaultDiffOptions)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestCompareValues(t *testing.T) {
+	left := map[string]any{
+		"name":  "app",
+		"ports": []any{80, 443},
+		"spec":  map[string]any{"replicas": 1, "image": "app:1"},
+	}
+	right := map[string]any{
+		"name":  "app",
+		"ports": []any{80, 8443},
+		"spec":  map[string]any{"replicas": 2},
+		"debug": true,
+	}
+
+	diffs, err := CompareValues(left, right, DefaultDiffOptions)
+	assert.NoError(t, err)
+	textDiffs, err := Compare(
+		[]byte("name: app\nports: [80, 443]\nspec:\n  replicas: 1\n  image: app:1\n"),
+		[]byte("name: app\nports: [80, 8443]\nspec:\n  replicas: 2\ndebug: true\n"),
+		false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, textDiffs[0].Paths(), diffs[0].Paths())
+	assert.Equal(t, DiffCount{Added: 1, Deleted: 1, Modified: 2}, diffs.Stat())
+
+	diffs, err = CompareValues([]any{"a", map[string]any{"b": 1}}, []any{"a", map[string]any{"b": 2}, "c"}, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"[1].b", "[2]"}}, diffs.Paths())
+
+	diffs, err = CompareValues(left, left, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.False(t, diffs.HasDiff())
+
+	_, err = CompareValues(left, make(chan int), DefaultDiffOptions)
+	assert.ErrorContains(t, err, "encoding right")
+}
+
+func TestCompareJSON(t *testing.T) {
+	diffs, err := Compare([]byte(`{"a":1}`), []byte("a: 2\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 1)
+	assert.Equal(t, Modified, diffs[0][0].Type())
+	assert.Equal(t, ".a", diffs.FormatFields())
+
+	left := []byte(`{
+  "name": "app",
+  "spec": {"ports": [80, 443], "labels": {"tier": "web"}}
+}`)
+	right := []byte(`
+name: app
+spec:
+  ports: [80, 8443]
+  labels: {}
+`)
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	expected := "~ spec.ports[1]: [line:3 <Integer>] 443 -> [line:4 <Integer>] 8443\n" +
+		"- spec.labels.tier: [line:3 <String>] \"web\""
+	assert.Equal(t, expected, diffs.Format(FormatOptions{Plain: true, Metadata: true}))
+
+	diffs, err = Compare([]byte("'a': 1\n\"b\": {c: 1}\n"), []byte("a: 1\nb: {\"c\": 2}\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"b.c"}}, diffs.Paths())
+}
+
+func TestCompareDocument(t *testing.T) {
+	leftAst, err := parser.ParseBytes([]byte("a: 1\n---\nb: 2\n---\nc: 3\n"), 0)
+	assert.NoError(t, err)
+	rightAst, err := parser.ParseBytes([]byte("a: 2\n---\nb: 2\n---\nc: 4\n---\nd: 5\n"), 0)
+	assert.NoError(t, err)
+
+	all, err := CompareAst(leftAst, rightAst, DefaultDiffOptions)
+	assert.NoError(t, err)
+	for i := range all {
+		diffs, err := CompareDocument(leftAst, rightAst, i, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Equal(t, all[i].Paths(), diffs.Paths(), i)
+	}
+
+	diffs, err := CompareDocument(leftAst, rightAst, 3, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.Equal(t, Added, diffs[0].Type())
+
+	_, err = CompareDocument(leftAst, rightAst, 4, DefaultDiffOptions)
+	assert.ErrorContains(t, err, "document index 4 is out of range")
+	_, err = CompareDocument(leftAst, rightAst, -1, DefaultDiffOptions)
+	assert.Error(t, err)
+}
+
 func TestFileDiffsHasDiff(t *testing.T) {
 	diffs, err := CompareFile(fileLeft, fileRight, false, DefaultDiffOptions)
 	assert.NoError(t, err)
 	assert.True(t, diffs.HasDiff())
+
+	diffs, err = CompareFile(fileLeft, fileLeft, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 1)
+	assert.False(t, diffs.HasDiff())
+	assert.False(t, FileDiffs{}.HasDiff())
+	assert.True(t, FileDiffs{nil, diffs[0], {&Diff{}}}.HasDiff())
 }
 
 func TestDiffsArray(t *testing.T) {
@@ -178,67 +576,2211 @@ func TestDiffsArray(t *testing.T) {
 	})
 }
 
-func TestFormat(t *testing.T) {
-	diffs, err := CompareFile(fileLeft, fileRight, false, DefaultDiffOptions)
-	assert.NoError(t, err)
+func TestCompareReorderedSequences(t *testing.T) {
+	tests := []struct {
+		left      string
+		right     string
+		reordered int
+	}{
+		{left: "items: [1, 2, 3]", right: "items: [1, 2, 3]", reordered: 0},
+		{left: "items: [1, 2, 3]", right: "items: [3, 2, 1]", reordered: 1},
+		{left: "items: [1, 2, 3, 4, 5]", right: "items: [4, 5]", reordered: 0},
+		{left: "items: [1, 2, 3]", right: "items: [1, 3, 4]", reordered: 0},
+		{left: "a: [1, 2]\nb: [x, y]\nc: [true]", right: "a: [2, 1]\nb: [y, x]\nc: [true]", reordered: 2},
+		{left: "items: [[1, 2], [3]]", right: "items: [[3], [2, 1]]", reordered: 2},
+	}
 
-	output := diffs.Format(FormatOptions{
-		Plain:    true,
-		Silent:   false,
-		Metadata: false,
-	})
+	for _, test := range tests {
+		leftAst, err := parser.ParseBytes([]byte(test.left), 0)
+		assert.NoError(t, err)
+		rightAst, err := parser.ParseBytes([]byte(test.right), 0)
+		assert.NoError(t, err)
 
-	assert.Equal(t, output, strings.Join(diffStringLines, "\n"))
+		result, err := CompareAstWithResult(leftAst, rightAst, DiffOptions{IgnoreSeqOrder: true})
+		assert.NoError(t, err)
+		assert.Len(t, result.Diffs, 1)
+		assert.Equal(t, test.reordered, result.ReorderedSequences, "%s <> %s", test.left, test.right)
+
+		result, err = CompareAstWithResult(leftAst, rightAst, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Zero(t, result.ReorderedSequences)
+	}
 }
 
-func ExampleCompare() {
+func TestCompareCoerceStringNumbers(t *testing.T) {
+	tests := []struct {
+		left          string
+		right         string
+		expectedDiffs int
+	}{
+		{left: `port: "8080"`, right: `port: 8080`, expectedDiffs: 0},
+		{left: `port: 8080`, right: `port: "8080"`, expectedDiffs: 0},
+		{left: `ratio: "0.5"`, right: `ratio: 0.5`, expectedDiffs: 0},
+		{left: `port: "8081"`, right: `port: 8080`, expectedDiffs: 1},
+		{left: `month: "08"`, right: `month: 8`, expectedDiffs: 1},
+		{left: `port: " 8080"`, right: `port: 8080`, expectedDiffs: 1},
+		{left: `port: "0x1F90"`, right: `port: 8080`, expectedDiffs: 1},
+	}
+
+	for _, test := range tests {
+		diffs, err := Compare([]byte(test.left), []byte(test.right), false, DiffOptions{CoerceStringNumbers: true})
+		assert.NoError(t, err)
+		assert.Len(t, diffs, 1)
+		assert.Len(t, diffs[0], test.expectedDiffs, "%s <> %s", test.left, test.right)
+
+		diffs, err = Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Len(t, diffs[0], 1, "%s <> %s", test.left, test.right)
+	}
+}
+
+func TestCompareCoerceScalarTypes(t *testing.T) {
+	tests := []struct {
+		left          string
+		right         string
+		expectedDiffs int
+	}{
+		{left: `port: "42"`, right: `port: 42`, expectedDiffs: 0},
+		{left: `port: 42`, right: `port: "42"`, expectedDiffs: 0},
+		{left: `ratio: "0.5"`, right: `ratio: 0.5`, expectedDiffs: 0},
+		{left: `enabled: "true"`, right: `enabled: true`, expectedDiffs: 0},
+		{left: `enabled: false`, right: `enabled: 'false'`, expectedDiffs: 0},
+		{left: `value: "null"`, right: `value: null`, expectedDiffs: 0},
+		{left: `value: "null"`, right: `value: ~`, expectedDiffs: 0},
+		{left: `value: ""`, right: `value: null`, expectedDiffs: 1},
+		{left: `value: "nil"`, right: `value: null`, expectedDiffs: 1},
+		{left: `enabled: "yes"`, right: `enabled: true`, expectedDiffs: 1},
+		{left: `month: "08"`, right: `month: 8`, expectedDiffs: 1},
+		{left: `month: "08"`, right: `month: 8.0`, expectedDiffs: 1},
+		{left: `ratio: "1e0"`, right: `ratio: 1`, expectedDiffs: 1},
+		{left: `ratio: "1e0"`, right: `ratio: 1.0`, expectedDiffs: 1},
+		{left: `ratio: "1"`, right: `ratio: 1.0`, expectedDiffs: 1},
+		{left: `ratio: "1.0"`, right: `ratio: 1.00`, expectedDiffs: 0},
+		{left: `ratio: "0.5"`, right: `ratio: .5`, expectedDiffs: 0},
+		{left: `port: "43"`, right: `port: 42`, expectedDiffs: 1},
+		{left: `enabled: 1`, right: `enabled: true`, expectedDiffs: 1},
+	}
+
+	for _, test := range tests {
+		diffs, err := Compare([]byte(test.left), []byte(test.right), false, DiffOptions{CoerceScalarTypes: true})
+		assert.NoError(t, err)
+		assert.Len(t, diffs[0], test.expectedDiffs, "%s <> %s", test.left, test.right)
+
+		diffs, err = Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Len(t, diffs[0], 1, "%s <> %s", test.left, test.right)
+	}
+}
+
+func TestCompareIntersectionOnly(t *testing.T) {
 	left := []byte(`
-name: Alice
-city:  New York
-items:
-	- one
-	- two
+name: app
+replicas: 1
+spec:
+  image: app:v1
+  port: 80
+`)
+	right := []byte(`
+name: app
+replicas: 2
+labels:
+  tier: web
+spec:
+  image: app:v2
+  debug: true
 `)
 
+	diffs, err := Compare(left, right, false, DiffOptions{IntersectionOnly: true})
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true})
+	assert.Equal(t, "~ replicas: 1 -> 2\n~ spec.image: app:v1 -> app:v2", output)
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 5)
+}
+
+func TestCompareIgnoreKeyCase(t *testing.T) {
+	left := []byte(`
+Name: app
+Spec:
+  Replicas: 1
+  image: app:v1
+`)
 	right := []byte(`
-name: Bob
-value: 990
-items:
-	- one
-	- three
+name: app
+spec:
+  replicas: 2
+  Image: app:v1
 `)
 
-	diffs, err := Compare(left, right, false, DefaultDiffOptions)
-	if err != nil {
-		panic(err)
-	}
+	diffs, err := Compare(left, right, false, DiffOptions{IgnoreKeyCase: true})
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true})
+	assert.Equal(t, "~ Spec.Replicas: 1 -> 2", output)
 
-	output := diffs.Format(FormatOptions{
-		Plain:    true,
-		Silent:   false,
-		Metadata: false,
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 4)
+
+	t.Run("conflicting keys", func(t *testing.T) {
+		left := []byte(`
+spec:
+  name: app
+  Name: other
+`)
+		right := []byte(`
+spec:
+  name: app
+`)
+
+		_, err := Compare(left, right, false, DiffOptions{IgnoreKeyCase: true})
+		assert.EqualError(t, err, "keys name and Name at spec differ only by case")
+
+		_, err = Compare(left, right, false, DefaultDiffOptions)
+		assert.NoError(t, err)
 	})
-	fmt.Println(output)
+}
 
-	// Output:
-	// ~ name: Alice -> Bob
-	// - city: New York
-	// + value: 990
-	// ~ items[1]: two -> three
+func TestCompareIgnoreValueCase(t *testing.T) {
+	left := []byte(`
+foo: Bar
+Mode: ENABLED
+count: 1
+`)
+	right := []byte(`
+foo: bar
+mode: enabled
+count: 2
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{IgnoreValueCase: true})
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true})
+	assert.Equal(t, "- Mode: ENABLED\n+ mode: enabled\n~ count: 1 -> 2", output)
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 4)
+	assert.Equal(t, Modified, diffs[0][0].Type())
+	assert.Equal(t, "foo", diffs[0][0].Path())
 }
 
-func toYaml(t *testing.T, a any) []byte {
-	b, err := yaml.Marshal(a)
-	if err != nil {
-		t.Error(err)
-	}
-	return b
+func TestCompareIgnoreScalarWhitespace(t *testing.T) {
+	left := []byte("name: \"alice \"\ncity: \" New  York\"\nport: 80\nscript: |\n  echo a \n  echo b\n")
+	right := []byte("name: \"alice\"\ncity: \"New York\"\nport: 8080\nscript: |\n  echo a\n  echo b\n")
+
+	diffs, err := Compare(left, right, false, DiffOptions{IgnoreScalarWhitespace: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"city", "port", "script"}, diffs[0].Paths())
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "city", "port", "script"}, diffs[0].Paths())
 }
 
-func readFile(t *testing.T, path string) []byte {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		t.Error(err)
-	}
-	return data
+func TestCompareNormalizeNewlines(t *testing.T) {
+	left := []byte("script: |\r\n  echo a\r\n  echo b\r\nnote: |\r\n  a  b\r\n")
+	right := []byte("script: |\n  echo a\n  echo b\nnote: |\n  a b\n")
+
+	diffs, err := Compare(left, right, false, DiffOptions{NormalizeNewlines: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"note"}, diffs[0].Paths())
+	assert.Equal(t, "~ note: |\n  a  b -> |\n  a b", diffs.Format(FormatOptions{Plain: true}))
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"script", "note"}, diffs[0].Paths())
+}
+
+func TestCompareResolveMergeKeys(t *testing.T) {
+	left := []byte(`
+defaults: &defaults
+  image: app:v1
+  replicas: 1
+labels: &labels
+  tier: backend
+web:
+  <<: *defaults
+  replicas: 3
+worker:
+  <<: [*labels, *defaults]
+  name: worker
+`)
+	right := []byte(`
+defaults:
+  image: app:v1
+  replicas: 1
+labels:
+  tier: backend
+web:
+  image: app:v1
+  replicas: 3
+worker:
+  name: worker
+  tier: backend
+  image: app:v1
+  replicas: 1
+`)
+
+	opts := DefaultDiffOptions
+	opts.ResolveMergeKeys = true
+	diffs, err := Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs[0])
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, diffs[0])
+
+	t.Run("merged value differs", func(t *testing.T) {
+		right := []byte(`
+defaults:
+  image: app:v1
+  replicas: 1
+labels:
+  tier: backend
+web:
+  image: app:v2
+  replicas: 3
+worker:
+  name: worker
+  tier: frontend
+  image: app:v1
+  replicas: 1
+`)
+
+		diffs, err := Compare(left, right, false, opts)
+		assert.NoError(t, err)
+		output := diffs.Format(FormatOptions{Plain: true})
+		assert.Equal(t, "~ web.image: app:v1 -> app:v2\n~ worker.tier: backend -> frontend", output)
+	})
+
+	t.Run("merged value deleted", func(t *testing.T) {
+		right := []byte(`
+defaults:
+  image: app:v1
+  replicas: 1
+web:
+  replicas: 3
+`)
+
+		diffs, err := Compare([]byte("defaults: &defaults\n  image: app:v1\n  replicas: 1\nweb:\n  <<: *defaults\n  replicas: 3\n"), right, false, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "- web.image: app:v1", diffs.Format(FormatOptions{Plain: true}))
+	})
+
+	t.Run("unknown anchor", func(t *testing.T) {
+		result, err := CompareWithResult([]byte("a:\n  <<: *missing\n  b: 1\n"), []byte("a:\n  b: 1\n"), false, opts)
+		assert.NoError(t, err)
+		assert.Empty(t, result.Diffs[0])
+		assert.Equal(t, []string{"unknown anchor missing of merge key at a.<<"}, result.Warnings)
+	})
+}
+
+func TestCompareExpandAliases(t *testing.T) {
+	left := []byte(`
+resources: &resources
+  cpu: 100m
+  memory: 128Mi
+ports: &ports [80, 443]
+web:
+  resources: *resources
+  ports: *ports
+worker:
+  resources: *resources
+`)
+	right := []byte(`
+resources:
+  cpu: 100m
+  memory: 128Mi
+ports: [80, 443]
+web:
+  resources:
+    cpu: 100m
+    memory: 128Mi
+  ports: [80, 443]
+worker:
+  resources: &worker
+    cpu: 100m
+    memory: 256Mi
+`)
+
+	opts := DefaultDiffOptions
+	opts.ExpandAliases = true
+	diffs, err := Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true})
+	assert.Equal(t, "~ worker.resources.memory: 128Mi -> 256Mi", output)
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 5)
+
+	t.Run("expanded value deleted", func(t *testing.T) {
+		left := []byte("resources: &resources\n  cpu: 100m\n  memory: 128Mi\nworker:\n  resources: *resources\n")
+		right := []byte("resources:\n  cpu: 100m\n  memory: 128Mi\nworker:\n  resources:\n    cpu: 100m\n")
+
+		diffs, err := Compare(left, right, false, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "- worker.resources.memory: 128Mi", diffs.Format(FormatOptions{Plain: true}))
+	})
+
+	t.Run("cyclic alias", func(t *testing.T) {
+		left := []byte(`
+node: &node
+  name: root
+  children:
+    - *node
+`)
+
+		_, err := Compare(left, left, false, opts)
+		assert.EqualError(t, err, "cyclic alias *node at node")
+
+		_, err = Compare(left, left, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+	})
+}
+
+func TestCompareSharedAnchors(t *testing.T) {
+	left := []byte(`
+defaults: &defaults
+  replicas: 1
+  image: app:v1
+---
+web:
+  <<: *defaults
+  name: web
+worker: *defaults
+`)
+	right := []byte(`
+defaults: &defaults
+  replicas: 1
+  image: app:v2
+---
+web:
+  replicas: 1
+  image: app:v1
+  name: web
+worker: *defaults
+`)
+
+	opts := DefaultDiffOptions
+	opts.ResolveMergeKeys = true
+	opts.ExpandAliases = true
+	diffs, err := Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ defaults.image: app:v1 -> app:v2\n---\n+ web.replicas: 1\n+ web.image: app:v1", diffs.Format(FormatOptions{Plain: true}))
+
+	opts.SharedAnchors = true
+	diffs, err = Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ defaults.image: app:v1 -> app:v2\n---\n~ worker.image: app:v1 -> app:v2", diffs.Format(FormatOptions{Plain: true}))
+
+	t.Run("anchor defined in the later document", func(t *testing.T) {
+		left := []byte("defaults: &defaults {image: app:v1}\n---\ndefaults: &defaults {image: app:v2}\nworker: *defaults\n")
+		right := []byte("defaults: &defaults {image: app:v1}\n---\ndefaults: {image: app:v2}\nworker: {image: app:v2}\n")
+
+		diffs, err := Compare(left, right, false, opts)
+		assert.NoError(t, err)
+		assert.False(t, diffs.HasDiff())
+	})
+}
+
+func TestCompareMergeKeysWithAliases(t *testing.T) {
+	left := []byte(`
+resources: &resources
+  limits:
+    cpu: 500m
+    memory: 256Mi
+base: &base
+  restartPolicy: Always
+  resources: *resources
+defaults: &defaults
+  <<: *base
+  image: registry.example.com/app:1.4.0
+  env: &env
+    - name: LOG_LEVEL
+      value: info
+services:
+  api:
+    <<: *defaults
+    replicas: 3
+    env: *env
+  worker:
+    <<: [*defaults]
+    image: registry.example.com/worker:1.4.0
+    restartPolicy: OnFailure
+`)
+	right := []byte(`
+resources:
+  limits:
+    cpu: 500m
+    memory: 256Mi
+base:
+  restartPolicy: Always
+  resources:
+    limits:
+      cpu: 500m
+      memory: 256Mi
+defaults:
+  restartPolicy: Always
+  resources:
+    limits:
+      cpu: 500m
+      memory: 256Mi
+  image: registry.example.com/app:1.4.0
+  env:
+    - name: LOG_LEVEL
+      value: info
+services:
+  api:
+    replicas: 3
+    env:
+      - name: LOG_LEVEL
+        value: info
+    image: registry.example.com/app:1.4.0
+    restartPolicy: Always
+    resources:
+      limits:
+        cpu: 500m
+        memory: 256Mi
+  worker:
+    image: registry.example.com/worker:1.4.0
+    restartPolicy: OnFailure
+    resources:
+      limits:
+        cpu: 500m
+        memory: 256Mi
+    env:
+      - name: LOG_LEVEL
+        value: info
+`)
+
+	opts := DefaultDiffOptions
+	opts.ResolveMergeKeys = true
+	opts.ExpandAliases = true
+	diffs, err := Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs[0])
+
+	// The differences within the merged aliases are reported under the paths of the services that use them.
+	changed := bytes.Replace(right, []byte("      limits:\n        cpu: 500m\n        memory: 256Mi\n    env:"), []byte("      limits:\n        cpu: 500m\n        memory: 512Mi\n    env:"), 1)
+	diffs, err = Compare(left, changed, false, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ services.worker.resources.limits.memory: 256Mi -> 512Mi", diffs.Format(FormatOptions{Plain: true}))
+}
+
+func TestCompareIgnoreIndices(t *testing.T) {
+	left := []byte(`
+rows:
+  - generated at 10:00
+  - a
+  - b
+other:
+  - generated at 10:00
+`)
+	right := []byte(`
+rows:
+  - generated at 11:00
+  - a
+  - c
+other:
+  - generated at 11:00
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{IgnoreIndices: map[string][]int{"rows": {0}}})
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true})
+	assert.Equal(t, "~ rows[2]: b -> c\n~ other[0]: generated at 10:00 -> generated at 11:00", output)
+
+	t.Run("unordered", func(t *testing.T) {
+		left := []byte(`
+- header v1
+- a
+- b
+`)
+		right := []byte(`
+- header v2
+- b
+- a
+`)
+		diffs, err := Compare(left, right, false, DiffOptions{IgnoreSeqOrder: true, IgnoreIndices: map[string][]int{"$": {0}}})
+		assert.NoError(t, err)
+		assert.Empty(t, diffs[0])
+	})
+
+	t.Run("missing item", func(t *testing.T) {
+		diffs, err := Compare([]byte("- a\n- b\n"), []byte("- c\n"), false, DiffOptions{IgnoreIndices: map[string][]int{"$": {0}}})
+		assert.NoError(t, err)
+		output := diffs.Format(FormatOptions{Plain: true})
+		assert.Equal(t, "- [1]: b", output)
+	})
+}
+
+func TestCompareIgnoreSeqOrderReorderedKeys(t *testing.T) {
+	left := []byte(`
+containers:
+  - name: app
+    image: app:v1
+    ports: [80, 443]
+  - name: sidecar
+    image: sidecar:v1
+`)
+	right := []byte(`
+containers:
+  - image: sidecar:v1
+    name: sidecar
+  - ports: [443, 80]
+    image: app:v1
+    name: app
+`)
+
+	result, err := CompareWithResult(left, right, false, DiffOptions{IgnoreSeqOrder: true})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Diffs[0])
+	assert.Equal(t, 2, result.ReorderedSequences)
+}
+
+func TestCompareStrictTypes(t *testing.T) {
+	left := []byte(`
+name: app
+spec:
+  replicas: 1
+  resources:
+    cpu: 100m
+`)
+	right := []byte(`
+name: app
+spec:
+  replicas: 2
+  resources: 100m
+`)
+
+	_, err := Compare(left, right, false, DiffOptions{StrictTypes: true})
+	assert.EqualError(t, err, "incompatible types at spec.resources: Mapping and String")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 2)
+
+	t.Run("unordered", func(t *testing.T) {
+		left := []byte("- 1\n- a: 1\n- text\n")
+		right := []byte("- a: 1\n- text\n- 2\n")
+		diffs, err := Compare(left, right, false, DiffOptions{StrictTypes: true, IgnoreSeqOrder: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "~ [0]: 1 -> 2", diffs.Format(FormatOptions{Plain: true}))
+	})
+}
+
+func TestCompareMatchByKeys(t *testing.T) {
+	left := []byte(`
+containers:
+  - name: app
+    image: app:v1
+    ports:
+      - containerPort: 80
+    volumeMounts:
+      - name: data
+        mountPath: /data
+      - name: data
+        mountPath: /cache
+  - name: sidecar
+    image: sidecar:v1
+`)
+	right := []byte(`
+containers:
+  - name: init
+    image: init:v1
+  - name: sidecar
+    image: sidecar:v1
+  - name: app
+    image: app:v2
+    ports:
+      - containerPort: 80
+    volumeMounts:
+      - name: data
+        mountPath: /cache
+      - name: data
+        mountPath: /data
+`)
+
+	result, err := CompareWithResult(left, right, false, DiffOptions{MatchByKeys: K8sListKeys})
+	assert.NoError(t, err)
+	output := result.Diffs.Format(FormatOptions{Plain: true})
+	assert.Equal(t, "+ containers[0]: \n    name: init\n    image: init:v1\n~ containers[0].image: app:v1 -> app:v2", output)
+	assert.Equal(t, 2, result.ReorderedSequences)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Greater(t, len(diffs[0]), 2)
+}
+
+func TestCompareKeyedSequences(t *testing.T) {
+	left := []byte(`
+spec:
+  containers:
+    - name: app
+      image: app:v1
+    - name: sidecar
+      image: sidecar:v1
+  volumes:
+    - name: data
+    - name: cache
+`)
+	right := []byte(`
+spec:
+  containers:
+    - name: sidecar
+      image: sidecar:v1
+    - name: app
+      image: app:v2
+  volumes:
+    - name: cache
+    - name: data
+`)
+
+	result, err := CompareWithResult(left, right, false, DiffOptions{KeyedSequences: map[string]string{"spec.containers": "name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spec.containers[0].image", "spec.volumes[0].name", "spec.volumes[1].name"}, result.Diffs[0].Paths())
+	assert.Equal(t, "app:v1", result.Diffs[0][0].leftNode.String())
+	assert.Equal(t, "app:v2", result.Diffs[0][0].rightNode.String())
+	assert.Equal(t, 1, result.ReorderedSequences)
+
+	diffs, err := Compare(left, right, false, DiffOptions{KeyedSequences: map[string]string{"spec.*": "name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spec.containers[0].image"}, diffs[0].Paths())
+}
+
+func TestCompareSequenceLCS(t *testing.T) {
+	tests := []struct {
+		name     string
+		left     string
+		right    string
+		expected string
+		naive    int
+	}{
+		{
+			name:     "pushed to the front",
+			left:     "items: [b, c, d]",
+			right:    "items: [a, b, c, d]",
+			expected: "+ items[0]: a",
+			naive:    4,
+		},
+		{
+			name:     "popped from the front",
+			left:     "items: [a, b, c, d]",
+			right:    "items: [b, c, d]",
+			expected: "- items[0]: a",
+			naive:    4,
+		},
+		{
+			name:     "pushed to the back",
+			left:     "items: [a, b]",
+			right:    "items: [a, b, c]",
+			expected: "+ items[2]: c",
+			naive:    1,
+		},
+		{
+			name:     "inserted and deleted in the middle",
+			left:     "items: [a, b, c, d, e]",
+			right:    "items: [a, x, b, d, e]",
+			expected: "- items[2]: c\n+ items[1]: x",
+			naive:    2,
+		},
+		{
+			name:     "changed item",
+			left:     "items:\n  - name: a\n    port: 80\n  - name: b\n    port: 81\n",
+			right:    "items:\n  - name: z\n    port: 90\n  - name: a\n    port: 80\n  - name: b\n    port: 82\n",
+			expected: "+ items[0]: \n    name: z\n    port: 90\n~ items[1].port: 81 -> 82",
+			naive:    5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs, err := Compare([]byte(test.left), []byte(test.right), false, DiffOptions{SequenceLCS: true})
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, diffs.Format(FormatOptions{Plain: true}))
+
+			diffs, err = Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+			assert.NoError(t, err)
+			assert.Equal(t, test.naive, diffs.Stat().Total())
+		})
+	}
+
+	t.Run("items equal by the options", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			left  string
+			right string
+			opts  DiffOptions
+		}{
+			{name: "value case", left: "items: [A, b, c]", right: "items: [x, a, b, c]", opts: DiffOptions{IgnoreValueCase: true}},
+			{name: "normalizer", left: "items: [yes, b, c]", right: "items: [x, true, b, c]", opts: DiffOptions{Normalizers: []ScalarNormalizer{BooleanNormalizer}}},
+			{name: "re-indented block scalar", left: "items:\n  - |\n    a\n  - b\n", right: "items:\n  - x\n  - |\n      a\n  - b\n", opts: DefaultDiffOptions},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				test.opts.SequenceLCS = true
+				diffs, err := Compare([]byte(test.left), []byte(test.right), false, test.opts)
+				assert.NoError(t, err)
+				assert.Equal(t, "+ items[0]: x", diffs.Format(FormatOptions{Plain: true}))
+			})
+		}
+	})
+}
+
+func TestLongestCommonSubsequence(t *testing.T) {
+	lcs := func(left, right []string) []int {
+		return longestCommonSubsequence(len(left), len(right), func(l, r int) bool { return left[l] == right[r] })
+	}
+	assert.Equal(t, []int{}, lcs([]string{}, []string{"a"}))
+	assert.Equal(t, []int{-1, -1}, lcs([]string{"a", "b"}, []string{}))
+	assert.Equal(t, []int{0, 1, 2}, lcs([]string{"a", "b", "c"}, []string{"a", "b", "c"}))
+	assert.Equal(t, []int{1, -1, 2, 4}, lcs([]string{"a", "b", "c", "d"}, []string{"x", "a", "c", "y", "d"}))
+	assert.Equal(t, []int{-1, 0, -1}, lcs([]string{"a", "b", "c"}, []string{"b", "a"}))
+}
+
+func TestCompareDetectMoves(t *testing.T) {
+	left := []byte("items: [a, b, c, d]\nports: [80, 443]\n")
+	right := []byte("items: [b, c, d, a]\nports: [8080, 80]\n")
+
+	diffs, err := Compare(left, right, false, DiffOptions{IgnoreSeqOrder: true, DetectMoves: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "↕ items[0→3]: a\n~ ports[1]: 443 -> 8080", diffs.Format(FormatOptions{Plain: true}))
+	assert.Equal(t, Moved, diffs[0][0].Type())
+	assert.Equal(t, "items[0]", diffs[0][0].Path())
+	from, to, ok := diffs[0][0].MovedIndexes()
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 3}, []int{from, to})
+	assert.Equal(t, DiffCount{Modified: 1, Moved: 1}, diffs.Stat())
+	assert.Equal(t, "0 added, 0 deleted, 1 modified, 1 moved", diffs.Stat().String())
+	assert.Equal(t, "↕ /items/0→3", diffs.Format(FormatOptions{Plain: true, PathsOnly: true, PathStyle: PathStylePointer, Transform: func(d *Diff) (*Diff, bool) {
+		return d, d.Type() == Moved
+	}}))
+
+	left = []byte(`
+containers:
+  - name: app
+    image: app:v1
+  - name: sidecar
+    image: sidecar:v1
+`)
+	right = []byte(`
+containers:
+  - name: sidecar
+    image: sidecar:v1
+  - name: app
+    image: app:v2
+`)
+	diffs, err = Compare(left, right, false, DiffOptions{MatchByKeys: []string{"name"}, DetectMoves: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "↕ containers[0→1]: \n    name: app\n    image: app:v2\n~ containers[0].image: app:v1 -> app:v2", diffs.Format(FormatOptions{Plain: true}))
+
+	diffs, err = Compare(left, right, false, DiffOptions{MatchByKeys: []string{"name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"containers[0].image"}, diffs[0].Paths())
+}
+
+func TestCompareIdenticalCollections(t *testing.T) {
+	tests := []struct {
+		left     string
+		right    string
+		expected []string
+	}{
+		{left: "a:\n  b: 1\n  c: [1, 2]\n", right: "a:\n  b: 1\n  c: [1, 2]\n", expected: []string{}},
+		{left: "a:\n  b: 1\n", right: "a:\n  b: 1\n  c:\n", expected: []string{"a.c"}},
+		{left: "k:\n  a:\n    b: 1\n  c: 2\n", right: "k:\n  a:\n    b: 1\n    c: 2\n", expected: []string{"k.c", "k.a.c"}},
+		{left: "a:\n  b: |\n    x\n    y\n", right: "a:\n  b: |\n    x\n    z\n", expected: []string{"a.b"}},
+		{left: "a:\n  - b: {c: 1}\n", right: "a:\n  - b: {c: 1, d: 2}\n", expected: []string{"a[0].b.d"}},
+	}
+	for _, test := range tests {
+		diffs, err := Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, diffs[0].Paths(), test.right)
+	}
+
+	// The line of a difference after an identical collection is not affected by skipping it.
+	diffs, err := Compare(deepMappingYaml(20, -1), deepMappingYaml(20, 10), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"services.service-10.image.tag"}, diffs[0].Paths())
+	assert.Equal(t, 10*9+5, diffs[0][0].rightNode.GetToken().Position.Line)
+}
+
+func TestCompareDetectRenames(t *testing.T) {
+	left := []byte(`
+spec:
+  oldName: x
+  resources:
+    limits:
+      cpu: 100m
+  removed: 1
+`)
+	right := []byte(`
+spec:
+  newName: x
+  limits:
+    limits:
+      cpu: 100m
+  added: 2
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{DetectRenames: true})
+	assert.NoError(t, err)
+	expected := "⟳ spec.oldName → spec.newName: x\n" +
+		"⟳ spec.resources → spec.limits: \n  limits:\n    cpu: 100m\n" +
+		"- spec.removed: 1\n" +
+		"+ spec.added: 2"
+	assert.Equal(t, expected, diffs.Format(FormatOptions{Plain: true}))
+	assert.Equal(t, Renamed, diffs[0][1].Type())
+	assert.Equal(t, "spec.resources", diffs[0][1].Path())
+	newPath, ok := diffs[0][1].RenamedPath()
+	assert.True(t, ok)
+	assert.Equal(t, "spec.limits", newPath)
+	assert.Equal(t, DiffCount{Added: 1, Deleted: 1, Renamed: 2}, diffs.Stat())
+
+	patch, err := diffs.JSONPatch()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "move", "from": "/spec/oldName", "path": "/spec/newName"},
+		{"op": "move", "from": "/spec/resources", "path": "/spec/limits"},
+		{"op": "remove", "path": "/spec/removed"},
+		{"op": "add", "path": "/spec/added", "value": 2}
+	]`, string(patch))
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, DiffCount{Added: 3, Deleted: 3}, diffs.Stat())
+}
+
+func TestCompareDetectKeyOrder(t *testing.T) {
+	left := []byte(`
+steps:
+  build: make
+  test: make test
+  deploy: make deploy
+env:
+  a: 1
+  b: 2
+changed:
+  x: 1
+  y: 2
+`)
+	right := []byte(`
+steps:
+  test: make test
+  build: make
+  deploy: make deploy
+env:
+  a: 1
+  b: 2
+changed:
+  y: 3
+  x: 1
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{DetectKeyOrder: true})
+	assert.NoError(t, err)
+	expected := "~ steps: (build, test, deploy) -> (test, build, deploy)\n" +
+		"~ changed.y: 2 -> 3"
+	assert.Equal(t, expected, diffs.Format(FormatOptions{Plain: true}))
+	assert.Equal(t, Modified, diffs[0][0].Type())
+
+	patch, err := diffs.JSONPatch()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"op": "replace", "path": "/changed/y", "value": 3}]`, string(patch))
+
+	diffs, err = Compare([]byte("a: {x: 1, y: 2}\nb: 1\n"), []byte("b: 1\na: {y: 2, x: 1}\n"), false, DiffOptions{DetectKeyOrder: true})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a"}}, diffs.Paths())
+
+	diffs, err = Compare([]byte("a: 1\nb: 2\n"), []byte("b: 2\nc: 3\n"), false, DiffOptions{DetectKeyOrder: true, IntersectionOnly: true})
+	assert.NoError(t, err)
+	assert.False(t, diffs.HasDiff())
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"changed.y"}}, diffs.Paths())
+}
+
+func TestCompareTreatNullAsAbsent(t *testing.T) {
+	left := []byte(`
+a: null
+b: 1
+spec:
+  c: ~
+  d:
+  items:
+    - e: null
+      f: 1
+g: null
+`)
+	right := []byte(`
+b: 1
+h: null
+spec:
+  items:
+    - f: 1
+  i:
+g: 2
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{TreatNullAsAbsent: true})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"g"}}, diffs.Paths())
+	assert.Equal(t, Modified, diffs[0][0].Type())
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, DiffCount{Added: 2, Deleted: 4, Modified: 1}, diffs.Stat())
+}
+
+func TestCompareMaxDepth(t *testing.T) {
+	left := []byte(`
+name: app
+spec:
+  containers:
+    - name: web
+      ports: [80, 443]
+      env:
+        - {name: A, value: "1"}
+    - name: sidecar
+      ports: [9000]
+  replicas: 1
+`)
+	right := []byte(`
+name: app
+spec:
+  containers:
+    - name: web
+      ports: [80, 8443]
+      env:
+        - {name: A, value: "2"}
+    - name: sidecar
+      ports: [9001]
+  replicas: 2
+`)
+
+	depth := func(n int) *int { return &n }
+	tests := []struct {
+		maxDepth *int
+		paths    []string
+	}{
+		{maxDepth: nil, paths: []string{"spec.containers[0].ports[1]", "spec.containers[0].env[0].value", "spec.containers[1].ports[0]", "spec.replicas"}},
+		{maxDepth: depth(6), paths: []string{"spec.containers[0].ports[1]", "spec.containers[0].env[0].value", "spec.containers[1].ports[0]", "spec.replicas"}},
+		{maxDepth: depth(5), paths: []string{"spec.containers[0].ports[1]", "spec.containers[0].env[0]", "spec.containers[1].ports[0]", "spec.replicas"}},
+		{maxDepth: depth(3), paths: []string{"spec.containers[0]", "spec.containers[1]", "spec.replicas"}},
+		{maxDepth: depth(2), paths: []string{"spec.containers", "spec.replicas"}},
+		{maxDepth: depth(1), paths: []string{"spec"}},
+		{maxDepth: depth(0), paths: []string{"$"}},
+	}
+	for _, test := range tests {
+		diffs, err := Compare(left, right, false, DiffOptions{MaxDepth: test.maxDepth})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{test.paths}, diffs.Paths())
+	}
+
+	diffs, err := Compare(left, right, false, DiffOptions{MaxDepth: depth(2)})
+	assert.NoError(t, err)
+	assert.Equal(t, Modified, diffs[0][0].Type())
+
+	diffs, err = Compare(left, left, false, DiffOptions{MaxDepth: depth(0)})
+	assert.NoError(t, err)
+	assert.False(t, diffs.HasDiff())
+}
+
+func TestCompareDeterministic(t *testing.T) {
+	var left, right strings.Builder
+	for i := 0; i < 50; i++ {
+		// Flow mappings put all the differences on the same line, so only the comparison decides their order.
+		left.WriteString(fmt.Sprintf("key%d: {a: %d, b: %d, c: %d}\n", i, i, i, i))
+		right.WriteString(fmt.Sprintf("key%d: {c: %d, b: %d, a: %d, d: %d}\n", i, i+1, i+1, i+1, i))
+	}
+
+	expected := ""
+	for i := 0; i < 10; i++ {
+		diffs, err := Compare([]byte(left.String()), []byte(right.String()), false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		output := diffs.Format(FormatOptions{Plain: true})
+		if i == 0 {
+			expected = output
+			assert.True(t, strings.HasPrefix(output, "~ key0.a: 0 -> 1\n~ key0.b: 0 -> 1\n~ key0.c: 0 -> 1\n+ key0.d: 0\n"), output)
+			continue
+		}
+		assert.Equal(t, expected, output)
+	}
+}
+
+func TestCompareWarnings(t *testing.T) {
+	left := []byte(`
+spec:
+  replicas: 1
+  replicas: 2
+items:
+  - a: 1
+    a: 1
+`)
+	right := []byte(`
+spec:
+  replicas: 2
+items:
+  - a: 1
+`)
+
+	result, err := CompareWithResult(left, right, false, DiffOptions{IgnoreSeqOrder: true})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Diffs[0])
+	assert.Equal(t, []string{
+		"duplicate key replicas at spec on line 4, only its last value is compared",
+		"duplicate key a at items[0] on line 7, only its last value is compared",
+	}, result.Warnings)
+
+	result, err = CompareWithResult(right, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Warnings)
+
+	t.Run("identical mappings", func(t *testing.T) {
+		left := []byte("a:\n  x: 1\n  x: 1\nb: 1\n")
+		result, err := CompareWithResult(left, []byte("a:\n  x: 1\n  x: 1\nb: 2\n"), false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Len(t, result.Diffs[0], 1)
+		assert.Equal(t, []string{"duplicate key x at a on line 3, only its last value is compared"}, result.Warnings)
+	})
+}
+
+func TestCompareJobs(t *testing.T) {
+	var left, right strings.Builder
+	for i := 0; i < 20; i++ {
+		left.WriteString(fmt.Sprintf("---\nindex: %d\nitems: [a, b, c]\nname: doc\nname: doc\n", i))
+		right.WriteString(fmt.Sprintf("---\nindex: %d\nitems: [c, b, a, %d]\n", i*2, i))
+	}
+
+	sequential, err := CompareWithResult([]byte(left.String()), []byte(right.String()), false, DiffOptions{IgnoreSeqOrder: true, Jobs: 1})
+	assert.NoError(t, err)
+	parallel, err := CompareWithResult([]byte(left.String()), []byte(right.String()), false, DiffOptions{IgnoreSeqOrder: true, Jobs: 4})
+	assert.NoError(t, err)
+
+	assert.Len(t, sequential.Diffs, 20)
+	assert.Equal(t, sequential.Diffs.Format(FormatOptions{Plain: true}), parallel.Diffs.Format(FormatOptions{Plain: true}))
+	assert.Equal(t, 20, sequential.ReorderedSequences)
+	assert.Equal(t, sequential.ReorderedSequences, parallel.ReorderedSequences)
+	assert.Equal(t, sequential.Warnings, parallel.Warnings)
+}
+
+func TestDiffModifiedKind(t *testing.T) {
+	left := []byte(`
+replicas: 1
+port: 80
+image: |
+  app:v1
+labels:
+  app: web
+args:
+  - --verbose
+name: app
+`)
+	right := []byte(`
+replicas: 2
+port: "80"
+image: app:v2
+labels:
+  - app=web
+args: --verbose
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	kinds := make(map[string]ModifiedKind)
+	for _, diff := range diffs[0] {
+		kinds[diff.Path()] = diff.ModifiedKind()
+	}
+	assert.Equal(t, map[string]ModifiedKind{
+		"replicas": ValueChange,
+		"port":     TypeChange,
+		"image":    ValueChange,
+		"labels":   CollectionRestructure,
+		"args":     TypeChange,
+		"name":     NotModified,
+	}, kinds)
+
+	t.Run("collapsed collections", func(t *testing.T) {
+		depth := 0
+		opts := DefaultDiffOptions
+		opts.MaxDepth = &depth
+		diffs, err := Compare([]byte("a: 1\n"), []byte("a: 2\n"), false, opts)
+		assert.NoError(t, err)
+		assert.Len(t, diffs[0], 1)
+		assert.Equal(t, ValueChange, diffs[0][0].ModifiedKind())
+
+		opts = DefaultDiffOptions
+		opts.DetectKeyOrder = true
+		diffs, err = Compare([]byte("a: 1\nb: 2\n"), []byte("b: 2\na: 1\n"), false, opts)
+		assert.NoError(t, err)
+		assert.Len(t, diffs[0], 1)
+		assert.Equal(t, ValueChange, diffs[0][0].ModifiedKind())
+	})
+}
+
+func TestDiffIsTypeChange(t *testing.T) {
+	left := []byte(`
+port: "8080"
+replicas: 1
+image: |
+  app:v1
+labels:
+  app: web
+args:
+  - --verbose
+enabled: true
+name: app
+`)
+	right := []byte(`
+port: 8080
+replicas: 2
+image: app:v2
+labels:
+  - app=web
+args: --verbose
+enabled: "true"
+tag: v1
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	typeChanges := make(map[string]bool)
+	for _, diff := range diffs[0] {
+		typeChanges[diff.Path()] = diff.IsTypeChange()
+	}
+	assert.Equal(t, map[string]bool{
+		"port":     true,
+		"replicas": false,
+		"image":    false,
+		"labels":   true,
+		"args":     true,
+		"enabled":  true,
+		"name":     false,
+		"tag":      false,
+	}, typeChanges)
+
+	output := diffs.Format(FormatOptions{Plain: true, Metadata: true})
+	assert.Contains(t, output, `~ port: [line:2 <String>] "8080" -> [line:2 <Integer>] (type changed) 8080`)
+	assert.Contains(t, output, "~ replicas: [line:3 <Integer>] 1 -> [line:3 <Integer>] 2\n")
+	assert.NotContains(t, diffs.Format(FormatOptions{Plain: true}), "(type changed)")
+}
+
+func TestDiffValues(t *testing.T) {
+	left := []byte(`
+replicas: 1
+name: 'app'
+labels:
+  app: web
+`)
+	right := []byte(`
+replicas: 2
+labels:
+  app: web
+  tier: backend
+ports:
+  - 80
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	values := make(map[string][2]string)
+	for _, diff := range diffs[0] {
+		values[diff.Path()] = [2]string{diff.OldValue(), diff.NewValue()}
+	}
+	assert.Equal(t, map[string][2]string{
+		"replicas":    {"1", "2"},
+		"name":        {"'app'", ""},
+		"labels.tier": {"", "backend"},
+		"ports":       {"", "\n  - 80"},
+	}, values)
+}
+
+func TestCompareCanonicalize(t *testing.T) {
+	left := []byte(`
+defaults: &defaults
+  image: app:v1
+  replicas: 1
+service:
+  <<: *defaults
+  ports: [80, 443]
+  mask: 0x1F
+---
+name: other
+`)
+	right := []byte(`
+service: {mask: 31, replicas: 1, image: "app:v1", ports: [80, 443]}
+defaults:
+  replicas: 1
+  image: 'app:v1'
+---
+name: other
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{Canonicalize: true})
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 2)
+	assert.Empty(t, diffs[0])
+	assert.Empty(t, diffs[1])
+
+	diffs, err = Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, diffs[0])
+
+	diffs, err = Compare(left, []byte("service: {mask: 30}\n---\nname: other\n"), false, DiffOptions{Canonicalize: true, IntersectionOnly: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "~ service.mask: 31 -> 30", diffs.Format(FormatOptions{Plain: true}))
+}
+
+func TestCompareNodes(t *testing.T) {
+	left, err := parser.ParseBytes([]byte(`
+kind: Deployment
+spec:
+  replicas: 1
+  image: app:v1
+`), 0)
+	assert.NoError(t, err)
+	right, err := parser.ParseBytes([]byte(`
+kind: StatefulSet
+spec:
+  replicas: 2
+  image: app:v1
+  paused: true
+`), 0)
+	assert.NoError(t, err)
+
+	leftSpec := left.Docs[0].Body.(*ast.MappingNode).Values[1].Value
+	rightSpec := right.Docs[0].Body.(*ast.MappingNode).Values[1].Value
+
+	diffs, err := CompareNodes(leftSpec, rightSpec, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ spec.replicas: 1 -> 2\n+ spec.paused: true", diffs.Format(FormatOptions{Plain: true}))
+
+	_, err = CompareNodes(leftSpec, rightSpec.(*ast.MappingNode).Values[0].Value, DiffOptions{StrictTypes: true})
+	assert.EqualError(t, err, "incompatible types at spec: Mapping and Integer")
+}
+
+func TestClosestMatch(t *testing.T) {
+	candidate := []byte(`
+replicas: 3
+image: app:v2
+env: production
+`)
+	baselines := map[string][]byte{
+		"staging": []byte(`
+replicas: 1
+image: app:v1
+env: staging
+debug: true
+`),
+		"production": []byte(`
+replicas: 3
+image: app:v1
+env: production
+`),
+	}
+
+	name, diffs, err := ClosestMatch(candidate, baselines, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "production", name)
+	assert.Equal(t, "~ image: app:v1 -> app:v2", diffs.Format(FormatOptions{Plain: true}))
+
+	_, _, err = ClosestMatch(candidate, nil, false, DefaultDiffOptions)
+	assert.EqualError(t, err, "no baselines to match")
+
+	baselines["broken"] = []byte("{a: 1")
+	_, _, err = ClosestMatch(candidate, baselines, false, DefaultDiffOptions)
+	assert.ErrorContains(t, err, "baseline broken:")
+}
+
+func TestFormat(t *testing.T) {
+	diffs, err := CompareFile(fileLeft, fileRight, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{
+		Plain:     true,
+		PathsOnly: false,
+		Metadata:  false,
+	})
+
+	assert.Equal(t, output, strings.Join(diffStringLines, "\n"))
+}
+
+func TestFormatPathStylePointer(t *testing.T) {
+	diffs, err := Compare([]byte("a:\n  b: [1, 2]\nc: 3\n"), []byte("a:\n  b: [1, 4]\nd: 5\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, PathStyle: PathStylePointer})
+	assert.Equal(t, "~ /a/b/1: 2 -> 4\n- /c: 3\n+ /d: 5", output)
+}
+
+func TestFormatCounts(t *testing.T) {
+	left := []byte(`
+a: 1
+b: 2
+---
+c: 3
+---
+d: 4
+`)
+	right := []byte(`
+a: 5
+e: 6
+---
+c: 3
+---
+f: 7
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, IncludeCounts: true, GrandTotal: true})
+	expected := `1 added, 1 deleted, 1 modified
+~ a: 1 -> 5
+- b: 2
++ e: 6
+---
+0 added, 0 deleted, 0 modified
+---
+1 added, 1 deleted, 0 modified
+- d: 4
++ f: 7
+
+total: 2 added, 2 deleted, 1 modified`
+	assert.Equal(t, expected, output)
+
+	output = diffs.Format(FormatOptions{Plain: true, PathsOnly: true, GrandTotal: true})
+	assert.Equal(t, "~ a\n- b\n+ e\n---\n- d\n+ f\n\ntotal: 2 added, 2 deleted, 1 modified", output)
+}
+
+func TestFormatCountsOnly(t *testing.T) {
+	left := []byte("a: 1\nb: 2\n---\nc: 3\n---\nd: 4\n")
+	right := []byte("a: 5\ne: 6\n---\nc: 3\n---\nf: 7\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, CountsOnly: true})
+	assert.Equal(t, "1 added, 1 deleted, 1 modified\n---\n0 added, 0 deleted, 0 modified\n---\n1 added, 1 deleted, 0 modified", output)
+
+	output = diffs.Format(FormatOptions{Plain: true, CountsOnly: true, ChangedDocsOnly: true, GrandTotal: true})
+	assert.Equal(t, "1 added, 1 deleted, 1 modified\n---\n1 added, 1 deleted, 0 modified\n\ntotal: 2 added, 2 deleted, 1 modified", output)
+
+	diffs, err = Compare([]byte("a: 1\n"), []byte("a: 1\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "0 added, 0 deleted, 0 modified", diffs.Format(FormatOptions{Plain: true, CountsOnly: true}))
+}
+
+type failingWriter struct {
+	limit int
+	err   error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, w.err
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestFormatTo(t *testing.T) {
+	left := []byte("a: 1\nb: 2\n---\nc: 3\n---\nd: 4\n")
+	right := []byte("a: 5\ne: 6\n---\nc: 3\n---\nf: 7\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	for _, opts := range []FormatOptions{
+		{Plain: true},
+		{Plain: true, IncludeCounts: true, GrandTotal: true},
+		{Plain: true, PathsOnly: true, GrandTotal: true, ChangedDocsOnly: true},
+		DefaultOutputOptions,
+	} {
+		var b bytes.Buffer
+		n, err := diffs.FormatTo(&b, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, diffs.Format(opts), b.String())
+		assert.Equal(t, int64(b.Len()), n)
+	}
+
+	var b bytes.Buffer
+	n, err := FileDiffs{DocDiffs{}}.FormatTo(&b, FormatOptions{Plain: true})
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	w := &failingWriter{limit: 5, err: io.ErrShortWrite}
+	n, err = diffs.FormatTo(w, FormatOptions{Plain: true})
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+	assert.Equal(t, int64(5), n)
+}
+
+func TestFormatASCIISymbols(t *testing.T) {
+	left := []byte("items: [a, b, c]\nold: x\nname: app\nconfig:\n  a: 1\n")
+	right := []byte("items: [b, c, a]\nnew: x\nname: web\nconfig: [1]\n")
+
+	diffs, err := Compare(left, right, false, DiffOptions{IgnoreSeqOrder: true, DetectMoves: true, DetectRenames: true})
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true, ASCIISymbols: true})
+	assert.Equal(t, "^ items[0->2]: a\n= old -> new: x\n~ name: app -> web\n~ config: \n  a: 1 -> \n  [1]", output)
+	for _, r := range output {
+		assert.Less(t, r, rune(128), output)
+	}
+
+	output = diffs.Format(FormatOptions{Plain: true, PathsOnly: true, PathStyle: PathStylePointer, ASCIISymbols: true})
+	assert.Equal(t, "^ /items/0->2\n= /old -> /new\n~ /name\n~ /config", output)
+
+	assert.Equal(t, "↕ items[0→2]: a\n⟳ old → new: x\n~ name: app -> web\n~ config: \n  a: 1 -> \n  [1]", diffs.Format(FormatOptions{Plain: true}))
+}
+
+func TestFormatMaxValueLines(t *testing.T) {
+	left := []byte(`
+script: |
+  echo 1
+  echo 2
+name: app
+`)
+	right := []byte(`
+script: |
+  echo 1
+  echo 2
+  echo 3
+name: web
+labels:
+  a: 1
+  b: 2
+  c: 3
+  d: 4
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		maxValueLines int
+		expected      string
+	}{
+		{
+			maxValueLines: 0,
+			expected:      "~ script: |\n  echo 1\n  echo 2 -> |\n  echo 1\n  echo 2\n  echo 3\n~ name: app -> web\n+ labels: \n  a: 1\n  b: 2\n  c: 3\n  d: 4",
+		},
+		{
+			maxValueLines: 4,
+			expected:      "~ script: |\n  echo 1\n  echo 2 -> |\n  echo 1\n  echo 2\n  echo 3\n~ name: app -> web\n+ labels: \n  a: 1\n  b: 2\n  c: 3\n  d: 4",
+		},
+		{
+			maxValueLines: 3,
+			expected:      "~ script: |\n  echo 1\n  echo 2 -> |\n  echo 1\n  echo 2\n  ... (+1 more line)\n~ name: app -> web\n+ labels: \n  a: 1\n  b: 2\n  c: 3\n  ... (+1 more line)",
+		},
+		{
+			maxValueLines: 1,
+			expected:      "~ script: |\n  ... (+2 more lines) -> |\n  ... (+3 more lines)\n~ name: app -> web\n+ labels: \n  a: 1\n  ... (+3 more lines)",
+		},
+	}
+	for _, test := range tests {
+		output := diffs.Format(FormatOptions{Plain: true, MaxValueLines: test.maxValueLines})
+		assert.Equal(t, test.expected, output, test.maxValueLines)
+	}
+}
+
+func TestFormatLiteral(t *testing.T) {
+	left := []byte(`
+script: |
+  echo hello
+  echo world
+description: >-
+  first line
+  second line
+`)
+	right := []byte(`
+script: |
+  echo hello
+    echo there
+description: >-
+  first line
+  second line
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs[0], 1)
+
+	output := diffs.Format(FormatOptions{Plain: true})
+	expected := `~ script: |
+  echo hello
+  echo world -> |
+  echo hello
+    echo there`
+	assert.Equal(t, expected, output)
+}
+
+func TestFormatExtendedMetadata(t *testing.T) {
+	left := []byte(`
+name: app
+`)
+	right := []byte(`
+name: app
+spec:
+  replicas: 2
+  containers:
+    - name: app
+      ports: [80, 443]
+    - name: sidecar
+  volumes: []
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, PathsOnly: false, Metadata: true, ExtendedMetadata: true})
+	assert.True(t, strings.HasPrefix(output, "+ spec: [line:4-9 <Mapping> leaves:6 depth:4] \n"), output)
+
+	output = diffs.Format(FormatOptions{Plain: true, PathsOnly: false, Metadata: true})
+	assert.True(t, strings.HasPrefix(output, "+ spec: [line:4-9 <Mapping>] \n"), output)
+}
+
+func TestFormatMetadataLineRange(t *testing.T) {
+	left := []byte(`name: app
+ports:
+  http: 80
+  https: 443
+---
+env: production
+`)
+	right := []byte(`name: app
+
+ports:
+  - 80
+  - 443
+  - 8080
+---
+env: staging
+flags: [a, b]
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true, Metadata: true, PathsOnly: false})
+	expected := `~ ports: [line:3-4 <Mapping>] 
+  http: 80
+  https: 443 -> [line:4-6 <Sequence>] (type changed) 
+  - 80
+  - 443
+  - 8080
+---
+~ env: [line:6 <String>] production -> [line:8 <String>] staging
++ flags: [line:9 <Sequence>] 
+  [a, b]`
+	assert.Equal(t, expected, output)
+}
+
+func TestFormatDocumentSeparators(t *testing.T) {
+	left := []byte(`
+a: 1
+---
+b: 2
+---
+c: 3
+---
+d: 4
+`)
+	right := []byte(`
+a: 1
+---
+b: 5
+---
+c: 3
+---
+d: 6
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Len(t, diffs, 4)
+	assert.Equal(t, "~ b: 2 -> 5\n---\n~ d: 4 -> 6", diffs.Format(FormatOptions{Plain: true}))
+
+	output := diffs.Format(FormatOptions{Plain: true, IncludeCounts: true})
+	assert.Equal(t, "0 added, 0 deleted, 0 modified\n---\n0 added, 0 deleted, 1 modified\n~ b: 2 -> 5\n---\n0 added, 0 deleted, 0 modified\n---\n0 added, 0 deleted, 1 modified\n~ d: 4 -> 6", output)
+
+	output = diffs.Format(FormatOptions{Plain: true, IncludeCounts: true, ChangedDocsOnly: true})
+	assert.Equal(t, "0 added, 0 deleted, 1 modified\n~ b: 2 -> 5\n---\n0 added, 0 deleted, 1 modified\n~ d: 4 -> 6", output)
+
+	diffs, err = Compare(left, left, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "", diffs.Format(FormatOptions{Plain: true}))
+	assert.Equal(t, "", diffs.Format(FormatOptions{Plain: true, IncludeCounts: true, ChangedDocsOnly: true}))
+}
+
+func TestFormatFields(t *testing.T) {
+	left := []byte(`
+metadata:
+  labels:
+    app.kubernetes.io/name: app
+spec:
+  containers:
+    - image: app:v1
+---
+kind: Service
+---
+data:
+  config.yaml: a
+`)
+	right := []byte(`
+metadata:
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  containers:
+    - image: app:v2
+      name: app
+---
+kind: Service
+---
+data:
+  config.yaml: b
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	expected := `.metadata.labels.app\.kubernetes\.io/name
+.spec.containers[0].image
+.spec.containers[0].name
+---
+.data.config\.yaml`
+	assert.Equal(t, expected, diffs.FormatFields())
+}
+
+func TestFormatCollectionSizes(t *testing.T) {
+	left := []byte(`
+name: app
+labels:
+  app: web
+`)
+	right := []byte(`
+name: app
+items:
+  - a
+  - b
+  - c
+resources:
+  cpu: 100m
+  memory: 1Gi
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, CollectionSizes: true})
+	expected := `- labels: (1 key)
+  app: web
++ items: (3 items)
+  - a
+  - b
+  - c
++ resources: (2 keys)
+  cpu: 100m
+  memory: 1Gi`
+	assert.Equal(t, expected, output)
+}
+
+func TestFormatTransform(t *testing.T) {
+	left := []byte(`
+spec:
+  password: secret1
+  replicas: 1
+  debug: true
+`)
+	right := []byte(`
+spec:
+  password: secret2
+  replicas: 2
+  tracing: true
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	transform := func(d *Diff) (*Diff, bool) {
+		if d.Type() == Deleted {
+			return nil, false
+		}
+		return d.WithPath(strings.TrimPrefix(d.Path(), "spec.")), true
+	}
+	output := diffs.Format(FormatOptions{Plain: true, Transform: transform})
+	assert.Equal(t, "~ password: secret1 -> secret2\n~ replicas: 1 -> 2\n+ tracing: true", output)
+
+	output = diffs.Format(FormatOptions{Plain: true, PathStyle: PathStylePointer, Transform: transform})
+	assert.Equal(t, "~ /password: secret1 -> secret2\n~ /replicas: 1 -> 2\n+ /tracing: true", output)
+
+	assert.Len(t, diffs[0], 4)
+	assert.Equal(t, "spec.password", diffs[0][0].Path())
+
+	// The documents and the total are counted by the differences that are left.
+	diffs, err = Compare([]byte("a: 1\n---\nb: 1\n"), []byte("a: 2\n---\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output = diffs.Format(FormatOptions{Plain: true, IncludeCounts: true, ChangedDocsOnly: true, GrandTotal: true, Transform: transform})
+	assert.Equal(t, "0 added, 0 deleted, 1 modified\n~ a: 1 -> 2\n\ntotal: 0 added, 0 deleted, 1 modified", output)
+}
+
+func TestFormatCollapseDepth(t *testing.T) {
+	left := []byte(`
+spec:
+  containers:
+    - name: app
+      image: app:v1
+      ports:
+        - containerPort: 80
+  replicas: 1
+  paused: false
+metadata:
+  name: app
+`)
+	right := []byte(`
+spec:
+  containers:
+    - name: app
+      image: app:v2
+      ports:
+        - containerPort: 8080
+  replicas: 2
+  strategy: Recreate
+metadata:
+  name: web
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, CollapseDepth: 1})
+	expected := "~ spec.containers[0].image: app:v1 -> app:v2 (+4 more under here)\n~ metadata.name: app -> web"
+	assert.Equal(t, expected, output)
+
+	output = diffs.Format(FormatOptions{Plain: true, CollapseDepth: 2})
+	expected = `~ spec.containers[0].image: app:v1 -> app:v2 (+1 more under here)
+~ spec.replicas: 1 -> 2
+- spec.paused: false
++ spec.strategy: Recreate
+~ metadata.name: app -> web`
+	assert.Equal(t, expected, output)
+
+	output = diffs.Format(FormatOptions{Plain: true, CollapseDepth: 1, IncludeCounts: true})
+	assert.True(t, strings.HasPrefix(output, "1 added, 1 deleted, 4 modified\n"))
+
+	assert.Equal(t, diffs.Format(FormatOptions{Plain: true}), diffs.Format(FormatOptions{Plain: true, CollapseDepth: 0}))
+}
+
+func TestFormatPathsOnly(t *testing.T) {
+	diffs, err := Compare([]byte("a: 1\nb: 2\n"), []byte("a: 3\nc: 4\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{
+		Plain:     true,
+		PathsOnly: true,
+		Metadata:  true,
+	})
+
+	assert.Equal(t, "~ a\n- b\n+ c", output)
+}
+
+func TestFormatGroupByType(t *testing.T) {
+	left := []byte("a: 1\nb: 2\nc: 3\nd: 4\n")
+	right := []byte("a: 1\nb: 5\ne: 6\nf: 7\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{
+		Plain:       true,
+		GroupByType: true,
+	})
+
+	expected := []string{
+		"Added:",
+		"+ e: 6",
+		"+ f: 7",
+		"Deleted:",
+		"- c: 3",
+		"- d: 4",
+		"Modified:",
+		"~ b: 2 -> 5",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), output)
+
+	t.Run("empty sections are omitted", func(t *testing.T) {
+		diffs, err := Compare([]byte("a: 1\n"), []byte("a: 2\n"), false, DefaultDiffOptions)
+		assert.NoError(t, err)
+
+		output := diffs.Format(FormatOptions{
+			Plain:       true,
+			GroupByType: true,
+		})
+		assert.Equal(t, "Modified:\n~ a: 1 -> 2", output)
+	})
+}
+
+func TestFormatWideSequence(t *testing.T) {
+	left := wideSequenceYaml(500, -1)
+	right := wideSequenceYaml(500, 250)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, Metadata: true})
+	assert.Equal(t, "~ items[250]: [line:252 <String>] item-250 -> [line:252 <String>] changed-250", output)
+}
+
+func BenchmarkFormatNoDiff(b *testing.B) {
+	left := wideSequenceYaml(5000, -1)
+	diffs, err := Compare(left, left, false, DefaultDiffOptions)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = diffs.Format(FormatOptions{Plain: true})
+		_ = diffs.FormatFields()
+	}
+}
+
+// BenchmarkCompareIdenticalDeepMapping compares a file of about 10k lines to itself, where the identical collections are skipped.
+func BenchmarkCompareIdenticalDeepMapping(b *testing.B) {
+	leftAst, err := parser.ParseBytes(deepMappingYaml(1111, -1), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rightAst, err := parser.ParseBytes(deepMappingYaml(1111, -1), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = CompareAst(leftAst, rightAst, DefaultDiffOptions)
+	}
+}
+
+func BenchmarkCompareDeepMapping(b *testing.B) {
+	leftAst, err := parser.ParseBytes(deepMappingYaml(1111, -1), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rightAst, err := parser.ParseBytes(deepMappingYaml(1111, 555), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = CompareAst(leftAst, rightAst, DefaultDiffOptions)
+	}
+}
+
+func BenchmarkFormatWideSequence(b *testing.B) {
+	leftAst, err := parser.ParseBytes(wideSequenceYaml(5000, -1), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	rightAst, err := parser.ParseBytes(wideSequenceYaml(5000, 2500), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diffs, _ := CompareAst(leftAst, rightAst, DefaultDiffOptions)
+		_ = diffs.Format(FormatOptions{Plain: true, Metadata: true})
+	}
+}
+
+func TestFormatPreserveQuotes(t *testing.T) {
+	left := []byte(`
+single: 'it''s'
+double: "say \"hi\""
+escaped: "a\tb"
+unicode: "caf\u00e9"
+plain: foo
+`)
+	right := []byte(`
+single: 'its'
+double: "say hi"
+escaped: "a b"
+unicode: "cafe"
+plain: "foo bar"
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, PreserveQuotes: true})
+	expected := []string{
+		`~ single: 'it''s' -> 'its'`,
+		`~ double: "say \"hi\"" -> "say hi"`,
+		`~ escaped: "a\tb" -> "a b"`,
+		`~ unicode: "café" -> "cafe"`,
+		`~ plain: foo -> "foo bar"`,
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), output)
+
+	output = diffs.Format(FormatOptions{Plain: true})
+	expected = []string{
+		`~ single: 'it''s' -> 'its'`,
+		`~ double: "say \"hi\"" -> "say hi"`,
+		`~ escaped: "a\\tb" -> "a b"`,
+		`~ unicode: "café" -> "cafe"`,
+		`~ plain: foo -> "foo bar"`,
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), output)
+}
+
+func TestFormatEmptyPlaceholder(t *testing.T) {
+	left := []byte(`
+name: app
+`)
+	right := []byte(`
+name: ""
+description: ""
+owner:
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	output := diffs.Format(FormatOptions{Plain: true, EmptyPlaceholder: "<empty>"})
+	expected := []string{
+		"~ name: app -> <empty>",
+		"+ description: <empty>",
+		"+ owner: <empty>",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), output)
+
+	output = diffs.Format(FormatOptions{Plain: true})
+	expected = []string{
+		`~ name: app -> ""`,
+		`+ description: ""`,
+		"+ owner: null",
+	}
+	assert.Equal(t, strings.Join(expected, "\n"), output)
+}
+
+func TestFileDiffsSortByPath(t *testing.T) {
+	left := []byte(`
+matrix: {b: 1, a: 1, c: 1}
+items: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11]
+`)
+	right := []byte(`
+matrix: {b: 2, a: 2, c: 2}
+items: [0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0]
+`)
+
+	expected := []string{
+		"~ items[0]: 1 -> 0",
+		"~ items[1]: 2 -> 0",
+		"~ items[2]: 3 -> 0",
+		"~ items[3]: 4 -> 0",
+		"~ items[4]: 5 -> 0",
+		"~ items[5]: 6 -> 0",
+		"~ items[6]: 7 -> 0",
+		"~ items[7]: 8 -> 0",
+		"~ items[8]: 9 -> 0",
+		"~ items[9]: 10 -> 0",
+		"~ items[10]: 11 -> 0",
+		"~ matrix.a: 1 -> 2",
+		"~ matrix.b: 1 -> 2",
+		"~ matrix.c: 1 -> 2",
+	}
+
+	for i := 0; i < 20; i++ {
+		diffs, err := Compare(left, right, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+
+		diffs.SortByPath()
+		output := diffs.Format(FormatOptions{Plain: true})
+		assert.Equal(t, strings.Join(expected, "\n"), output)
+	}
+}
+
+func ExampleCompare() {
+	left := []byte(`
+name: Alice
+city:  New York
+items:
+	- one
+	- two
+`)
+
+	right := []byte(`
+name: Bob
+value: 990
+items:
+	- one
+	- three
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	if err != nil {
+		panic(err)
+	}
+
+	output := diffs.Format(FormatOptions{
+		Plain:     true,
+		PathsOnly: false,
+		Metadata:  false,
+	})
+	fmt.Println(output)
+
+	// Output:
+	// ~ name: Alice -> Bob
+	// - city: New York
+	// + value: 990
+	// ~ items[1]: two -> three
+}
+
+func ExampleFileDiffs_Paths() {
+	diffs, err := CompareFile("testdata/file-left.yaml", "testdata/file-right.yaml", false, DefaultDiffOptions)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, path := range diffs.Paths()[0] {
+		fmt.Println(path)
+	}
+
+	// Output:
+	// people.name
+	// people.surname
+	// city.name
+	// item.id
+	// item.price
+}
+
+func toYaml(t *testing.T, a any) []byte {
+	b, err := yaml.Marshal(a)
+	if err != nil {
+		t.Error(err)
+	}
+	return b
+}
+
+// wideSequenceYaml returns a document holding a single sequence of n scalar items,
+// where the item at the changed index, if any, has a different value.
+func wideSequenceYaml(n, changed int) []byte {
+	var b strings.Builder
+	b.WriteString("items:\n")
+	for i := 0; i < n; i++ {
+		if i == changed {
+			fmt.Fprintf(&b, "  - changed-%d\n", i)
+		} else {
+			fmt.Fprintf(&b, "  - item-%d\n", i)
+		}
+	}
+	return []byte(b.String())
+}
+
+// deepMappingYaml returns a document of n services, each one a nested mapping of 10 lines,
+// where the image of the service at the changed index, if any, has a different tag.
+func deepMappingYaml(n, changed int) []byte {
+	var b strings.Builder
+	b.WriteString("services:\n")
+	for i := 0; i < n; i++ {
+		tag := "v1"
+		if i == changed {
+			tag = "v2"
+		}
+		fmt.Fprintf(&b, "  service-%d:\n    image:\n      repository: app-%d\n      tag: %s\n", i, i, tag)
+		fmt.Fprintf(&b, "    resources:\n      limits:\n        cpu: 100m\n        memory: 128Mi\n    replicas: %d\n", i%3)
+	}
+	return []byte(b.String())
+}
+
+func readFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+	}
+	return data
+}
+
+func TestCompareComments(t *testing.T) {
+	left := []byte(`
+# settings
+name: app # the name
+replicas: 1 # scaled later
+ports:
+  - 80 # http
+image: app:v1 # pinned
+`)
+	right := []byte(`
+# settings
+name: app # the name of the app
+replicas: 1
+ports:
+  - 80 # http
+image: app:v2 # pinned
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "~ image: app:v1 -> app:v2", diffs.Format(FormatOptions{Plain: true}))
+
+	opts := DefaultDiffOptions
+	opts.CompareComments = true
+	diffs, err = Compare(left, right, false, opts)
+	assert.NoError(t, err)
+	expected := `~ name: # the name -> # the name of the app
+~ replicas: # scaled later -> <none>
+~ image: app:v1 # pinned -> app:v2 # pinned`
+	assert.Equal(t, expected, diffs.Format(FormatOptions{Plain: true}))
+	assert.Equal(t, Modified, diffs[0][0].Type())
+	assert.Equal(t, "name", diffs[0][0].Path())
+
+	diffs, err = Compare(left, left, false, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, diffs[0])
+}
+
+func TestFormatColorPercent(t *testing.T) {
+	left := []byte("ratio: 100%\n")
+	right := []byte("ratio: 100%d\n")
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "\x1b[93m~\x1b[0m \x1b[93mratio\x1b[0m: \x1b[97m100%\x1b[0m -> \x1b[97m100%d\x1b[0m", diffs.Format(FormatOptions{}))
+	assert.Equal(t, "\x1b[91mratio: 100%\x1b[0m \x1b[93m|\x1b[0m \x1b[92mratio: 100%d\x1b[0m", FormatFull(left, right, diffs, FormatOptions{}))
+}
+
+func TestFormatColorConcurrently(t *testing.T) {
+	left := []byte("a: 1\nb: 2\n")
+	right := []byte("a: 1\nb: 3\n")
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+
+	// The output is colored unless it is plain, whatever color.NoColor holds, even when both are formatted at the same time.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "~ b: 2 -> 3", diffs.Format(FormatOptions{Plain: true}))
+			assert.Equal(t, " a: 1\n-b: 2\n+b: 3", FormatUnified(left, right, diffs, FormatOptions{Plain: true}))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "\x1b[93m~\x1b[0m \x1b[93mb\x1b[0m: \x1b[97m2\x1b[0m -> \x1b[97m3\x1b[0m", diffs.Format(FormatOptions{}))
+			assert.Equal(t, " a: 1\n\x1b[91m-b: 2\x1b[0m\n\x1b[92m+b: 3\x1b[0m", FormatUnified(left, right, diffs, FormatOptions{}))
+		}()
+	}
+	wg.Wait()
 }
@@ -0,0 +1,151 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// Explanation describes the nodes at a single path on both sides of a document and how they compare.
+type Explanation struct {
+	// Path is the explained path, such as "spec.containers[0].image".
+	Path string
+	// Left is the node at the path in the left document, or nil if there is none.
+	Left ast.Node
+	// Right is the node at the path in the right document, or nil if there is none.
+	Right ast.Node
+	// Diffs are the differences at and under the path.
+	Diffs DocDiffs
+}
+
+// Reason returns why the nodes at the path differ, or "equal" if they do not.
+func (e *Explanation) Reason() string {
+	switch {
+	case e.Left == nil && e.Right == nil:
+		return "missing on both sides"
+	case e.Left == nil:
+		return "missing on the left side"
+	case e.Right == nil:
+		return "missing on the right side"
+	case len(e.Diffs) == 0:
+		return "equal"
+	}
+	leftType := wrapMappingValueNode(e.Left).Type()
+	rightType := wrapMappingValueNode(e.Right).Type()
+	switch {
+	case leftType != rightType:
+		return fmt.Sprintf("type mismatch: %s and %s", leftType, rightType)
+	case leftType == ast.MappingType || leftType == ast.SequenceType:
+		return fmt.Sprintf("%d difference(s) under the path", len(e.Diffs))
+	}
+	return "value mismatch"
+}
+
+// Format returns the line, type and value of the node on each side of the path, followed by the reason of the difference.
+func (e *Explanation) Format(opts FormatOptions) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("path: %s\n", e.Path))
+	sb.WriteString(fmt.Sprintf("left: %s\n", explainNode(e.Left, opts)))
+	sb.WriteString(fmt.Sprintf("right: %s\n", explainNode(e.Right, opts)))
+	reason := e.Reason()
+	if !opts.Plain && len(e.Diffs) > 0 {
+		reason = hiYellowString(reason)
+	}
+	sb.WriteString(fmt.Sprintf("result: %s", reason))
+	return sb.String()
+}
+
+func explainNode(n ast.Node, opts FormatOptions) string {
+	if n == nil {
+		return "<none>"
+	}
+	n = wrapMappingValueNode(n)
+	return fmt.Sprintf("%s %s", nodeMetadata(n), nodeValueString(n, opts))
+}
+
+// Explain compares the nodes at the given path in each document of the yaml files provided as bytes,
+// and returns an Explanation per document.
+func Explain(left []byte, right []byte, path string, comments bool, opts DiffOptions) ([]*Explanation, error) {
+	var parserMode parser.Mode
+	if comments || opts.CompareComments {
+		parserMode |= parser.ParseComments
+	}
+
+	leftAst, err := parseBytes(left, parserMode)
+	if err != nil {
+		return nil, fmt.Errorf("parsing left: %w", err)
+	}
+
+	rightAst, err := parseBytes(right, parserMode)
+	if err != nil {
+		return nil, fmt.Errorf("parsing right: %w", err)
+	}
+
+	return ExplainAst(leftAst, rightAst, path, opts)
+}
+
+// ExplainAst is like Explain, but it works on the yaml documents represented as ASTs.
+func ExplainAst(left *ast.File, right *ast.File, path string, opts DiffOptions) ([]*Explanation, error) {
+	segments := splitPath(path)
+	leftDocs := documents(left)
+	rightDocs := documents(right)
+	leftShared := sharedAnchors(leftDocs, opts)
+	rightShared := sharedAnchors(rightDocs, opts)
+	explanations := make([]*Explanation, max(len(leftDocs), len(rightDocs)))
+	for i := range explanations {
+		e := &Explanation{Path: path}
+		var leftBody, rightBody ast.Node
+		if len(leftDocs) > i {
+			leftBody = leftDocs[i].Body
+			e.Left = lookupNode(leftBody, segments, opts)
+		}
+		if len(rightDocs) > i {
+			rightBody = rightDocs[i].Body
+			e.Right = lookupNode(rightBody, segments, opts)
+		}
+		if e.Left != nil || e.Right != nil {
+			c := newComparator(opts)
+			c.resolveAnchors(leftBody, rightBody, leftShared, rightShared)
+			e.Diffs = c.compareNodes(e.Left, e.Right)
+			if c.err != nil {
+				return nil, c.err
+			}
+		}
+		explanations[i] = e
+	}
+	return explanations, nil
+}
+
+// lookupNode returns the node under the given node that the path segments lead to, or nil if there is none.
+func lookupNode(n ast.Node, segments []pathSegment, opts DiffOptions) ast.Node {
+	for _, segment := range segments {
+		if n == nil {
+			return nil
+		}
+		n = childNode(wrapMappingValueNode(n), segment, opts)
+	}
+	return n
+}
+
+// childNode returns the item of the sequence or the value of the mapping that the path segment points to.
+func childNode(n ast.Node, segment pathSegment, opts DiffOptions) ast.Node {
+	switch n := n.(type) {
+	case *ast.SequenceNode:
+		if segment.isIndex && segment.index >= 0 && segment.index < len(n.Values) {
+			return n.Values[segment.index]
+		}
+	case *ast.MappingNode:
+		if segment.isIndex {
+			return nil
+		}
+		for _, value := range n.Values {
+			key := keyString(value.Key)
+			if key == segment.key || (opts.IgnoreKeyCase && strings.EqualFold(key, segment.key)) {
+				return value.Value
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	left := []byte(`
+spec:
+  replicas: 1
+  image: app:v1
+  ports: [80, 443]
+`)
+	right := []byte(`
+spec:
+  replicas: "1"
+  image: app:v2
+  ports: [80, 443]
+`)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path:     ".spec.replicas",
+			expected: "path: .spec.replicas\nleft: [line:3 <Integer>] 1\nright: [line:3 <String>] \"1\"\nresult: type mismatch: Integer and String",
+		},
+		{
+			path:     "spec.image",
+			expected: "path: spec.image\nleft: [line:4 <String>] app:v1\nright: [line:4 <String>] app:v2\nresult: value mismatch",
+		},
+		{
+			path:     "spec.ports[1]",
+			expected: "path: spec.ports[1]\nleft: [line:5 <Integer>] 443\nright: [line:5 <Integer>] 443\nresult: equal",
+		},
+		{
+			path:     "spec.command",
+			expected: "path: spec.command\nleft: <none>\nright: <none>\nresult: missing on both sides",
+		},
+		{
+			path:     "spec.ports[-1]",
+			expected: "path: spec.ports[-1]\nleft: <none>\nright: <none>\nresult: missing on both sides",
+		},
+	}
+
+	for _, test := range tests {
+		explanations, err := Explain(left, right, test.path, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		assert.Len(t, explanations, 1)
+		assert.Equal(t, test.expected, explanations[0].Format(FormatOptions{Plain: true}), test.path)
+	}
+
+	explanations, err := Explain(left, right, "spec", false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "2 difference(s) under the path", explanations[0].Reason())
+	assert.Len(t, explanations[0].Diffs, 2)
+}
@@ -0,0 +1,97 @@
+package compare
+
+import (
+	"fmt"
+	"path"
+)
+
+// pathFilter keeps the differences whose paths match the include patterns, if any, and none of the exclude patterns.
+type pathFilter struct {
+	include [][]pathSegment
+	exclude [][]pathSegment
+}
+
+// newPathFilter parses the include and exclude patterns, or returns an error if any of them is malformed.
+func newPathFilter(include, exclude []string) (*pathFilter, error) {
+	f := &pathFilter{}
+	var err error
+	if f.include, err = parsePathPatterns(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = parsePathPatterns(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func parsePathPatterns(patterns []string) ([][]pathSegment, error) {
+	parsed := make([][]pathSegment, 0, len(patterns))
+	for _, pattern := range patterns {
+		segments := splitPath(pattern)
+		for _, segment := range segments {
+			if _, err := path.Match(segment.key, ""); err != nil {
+				return nil, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+			}
+		}
+		parsed = append(parsed, segments)
+	}
+	return parsed, nil
+}
+
+// filter returns the differences that the filter keeps. The exclude patterns win over the include patterns.
+func (f *pathFilter) filter(diffs DocDiffs) DocDiffs {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return diffs
+	}
+	filtered := make(DocDiffs, 0, len(diffs))
+	for _, diff := range diffs {
+		segments := splitPath(diff.Path())
+		if len(f.include) > 0 && !matchAnyPathPattern(f.include, segments) {
+			continue
+		}
+		if matchAnyPathPattern(f.exclude, segments) {
+			continue
+		}
+		filtered = append(filtered, diff)
+	}
+	return filtered
+}
+
+func matchAnyPathPattern(patterns [][]pathSegment, segments []pathSegment) bool {
+	for _, pattern := range patterns {
+		if matchPathPattern(pattern, segments) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathPattern reports whether the path segments are at or under a path that the pattern matches.
+// A key of the pattern may hold the wildcards of path.Match, such as "*" or "app-*", and "*" or "[*]" matches any key or index.
+func matchPathPattern(pattern, segments []pathSegment) bool {
+	if len(segments) < len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		s := segments[i]
+		switch {
+		case p.isIndex:
+			if !s.isIndex || s.index != p.index {
+				return false
+			}
+		case p.key == "*":
+		case s.isIndex:
+			return false
+		default:
+			if ok, _ := path.Match(p.key, s.key); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchExactPathPattern reports whether the pattern matches the path segments themselves, rather than a path above them.
+func matchExactPathPattern(pattern, segments []pathSegment) bool {
+	return len(pattern) == len(segments) && matchPathPattern(pattern, segments)
+}
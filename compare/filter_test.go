@@ -0,0 +1,104 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareIncludeExcludePaths(t *testing.T) {
+	left := []byte(`
+metadata:
+  name: app
+  annotations:
+    revision: "1"
+spec:
+  replicas: 1
+  containers:
+    - name: app
+      image: app:v1
+    - name: sidecar
+      image: proxy:v1
+  app-labels:
+    tier: backend
+`)
+	right := []byte(`
+metadata:
+  name: web
+  annotations:
+    revision: "2"
+spec:
+  replicas: 2
+  containers:
+    - name: app
+      image: app:v2
+    - name: sidecar
+      image: proxy:v2
+  app-labels:
+    tier: frontend
+`)
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "no patterns",
+			expected: []string{"metadata.name", "metadata.annotations.revision", "spec.replicas", "spec.containers[0].image", "spec.containers[1].image", "spec.app-labels.tier"},
+		},
+		{
+			name:     "include",
+			include:  []string{"spec"},
+			expected: []string{"spec.replicas", "spec.containers[0].image", "spec.containers[1].image", "spec.app-labels.tier"},
+		},
+		{
+			name:     "exclude",
+			exclude:  []string{".metadata.annotations"},
+			expected: []string{"metadata.name", "spec.replicas", "spec.containers[0].image", "spec.containers[1].image", "spec.app-labels.tier"},
+		},
+		{
+			name:     "exclude wins over include",
+			include:  []string{"spec", "metadata"},
+			exclude:  []string{"spec.containers", "metadata.*"},
+			expected: []string{"spec.replicas", "spec.app-labels.tier"},
+		},
+		{
+			name:     "index wildcard",
+			include:  []string{"spec.containers[*].image"},
+			expected: []string{"spec.containers[0].image", "spec.containers[1].image"},
+		},
+		{
+			name:     "index",
+			include:  []string{"spec.containers[1]"},
+			expected: []string{"spec.containers[1].image"},
+		},
+		{
+			name:     "key wildcard",
+			include:  []string{"spec.app-*", "*.name"},
+			expected: []string{"metadata.name", "spec.app-labels.tier"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultDiffOptions
+			opts.IncludePaths = test.include
+			opts.ExcludePaths = test.exclude
+			diffs, err := Compare(left, right, false, opts)
+			assert.NoError(t, err)
+			paths := make([]string, 0, len(diffs[0]))
+			for _, diff := range diffs[0] {
+				paths = append(paths, diff.Path())
+			}
+			assert.Equal(t, test.expected, paths)
+		})
+	}
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		opts := DefaultDiffOptions
+		opts.ExcludePaths = []string{`spec.app\`}
+		_, err := Compare(left, right, false, opts)
+		assert.EqualError(t, err, `invalid path pattern "spec.app\\": syntax error in pattern`)
+	})
+}
@@ -0,0 +1,31 @@
+package compare
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ExtractFrontMatter returns the yaml front matter of the document, such as a markdown file,
+// which is the block between the "---" line at the very beginning of the document and the next "---" or "..." line.
+// The opening line is kept as an empty line, so the lines in the differences match the lines in the document.
+func ExtractFrontMatter(document []byte) ([]byte, error) {
+	document = bytes.TrimPrefix(document, []byte("\xef\xbb\xbf"))
+	lines := bytes.SplitAfter(document, []byte("\n"))
+	if len(lines) == 0 || !isFrontMatterFence(lines[0], "---") {
+		return nil, errors.New("no front matter found at the beginning of the document")
+	}
+	for i := 1; i < len(lines); i++ {
+		if isFrontMatterFence(lines[i], "---") || isFrontMatterFence(lines[i], "...") {
+			frontMatter := []byte("\n")
+			for _, line := range lines[1:i] {
+				frontMatter = append(frontMatter, line...)
+			}
+			return frontMatter, nil
+		}
+	}
+	return nil, errors.New("front matter is not closed")
+}
+
+func isFrontMatterFence(line []byte, fence string) bool {
+	return string(bytes.TrimRight(line, " \t\r\n")) == fence
+}
@@ -0,0 +1,44 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractFrontMatter(t *testing.T) {
+	left := []byte(`---
+title: Getting Started
+tags: [intro, setup]
+draft: true
+---
+# Getting Started
+
+Some content.
+`)
+	right := []byte(`---
+title: Getting Started
+tags: [intro, install]
+...
+# Getting Started
+
+Other content.
+`)
+
+	leftFrontMatter, err := ExtractFrontMatter(left)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ntitle: Getting Started\ntags: [intro, setup]\ndraft: true\n", string(leftFrontMatter))
+	rightFrontMatter, err := ExtractFrontMatter(right)
+	assert.NoError(t, err)
+
+	diffs, err := Compare(leftFrontMatter, rightFrontMatter, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output := diffs.Format(FormatOptions{Plain: true, Metadata: true})
+	assert.Equal(t, "~ tags[1]: [line:3 <String>] setup -> [line:3 <String>] install\n- draft: [line:4 <Bool>] true", output)
+
+	_, err = ExtractFrontMatter([]byte("# Title\n---\na: 1\n---\n"))
+	assert.EqualError(t, err, "no front matter found at the beginning of the document")
+
+	_, err = ExtractFrontMatter([]byte("---\na: 1\n"))
+	assert.EqualError(t, err, "front matter is not closed")
+}
@@ -0,0 +1,166 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// FormatFull renders both yaml files side by side, the left one in the first column and the right one in the second,
+// with a marker between the columns on each line, following the layout of `diff --side-by-side`:
+//
+//	' ' the lines are not changed
+//	'|' the lines are changed
+//	'<' the line exists only on the left side
+//	'>' the line exists only on the right side
+//
+// The diffs are expected to be the result of comparing the given files, as they determine which lines are changed.
+// Unchanged lines are aligned with each other, so the output is a self-contained record of both files and their differences.
+func FormatFull(left, right []byte, diffs FileDiffs, opts FormatOptions) string {
+	leftLines := splitLines(left)
+	rightLines := splitLines(right)
+	leftChanged := make(map[int]bool)
+	rightChanged := make(map[int]bool)
+	for _, docDiffs := range diffs {
+		for _, diff := range docDiffs {
+			markLines(leftChanged, diff.leftNode)
+			markLines(rightChanged, diff.rightNode)
+		}
+	}
+
+	width := 0
+	for _, line := range leftLines {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+
+	rows := make([]string, 0, max(len(leftLines), len(rightLines)))
+	for _, pair := range alignLines(leftLines, rightLines, leftChanged, rightChanged) {
+		var leftLine, rightLine string
+		marker := " "
+		switch {
+		case pair[0] == -1:
+			rightLine = rightLines[pair[1]]
+			marker = ">"
+		case pair[1] == -1:
+			leftLine = leftLines[pair[0]]
+			marker = "<"
+		default:
+			leftLine = leftLines[pair[0]]
+			rightLine = rightLines[pair[1]]
+			if leftChanged[pair[0]] || rightChanged[pair[1]] {
+				marker = "|"
+			}
+		}
+
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(leftLine))
+		if !opts.Plain {
+			if pair[0] != -1 && leftChanged[pair[0]] {
+				leftLine = hiRedString(leftLine)
+			}
+			if pair[1] != -1 && rightChanged[pair[1]] {
+				rightLine = hiGreenString(rightLine)
+			}
+			if marker != " " {
+				marker = hiYellowString(marker)
+			}
+		}
+		rows = append(rows, strings.TrimRight(fmt.Sprintf("%s%s %s %s", leftLine, padding, marker, rightLine), " "))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func splitLines(b []byte) []string {
+	s := strings.TrimSuffix(string(b), "\n")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, "\n")
+}
+
+// markLines marks the zero-based indexes of the lines that the node spans.
+func markLines(lines map[int]bool, n ast.Node) {
+	if n == nil {
+		return
+	}
+	start, end := nodeLineRange(n)
+	for line := start; line <= end; line++ {
+		lines[line-1] = true
+	}
+}
+
+// nodeLineRange returns the first and the last line of the node, including all of its children.
+func nodeLineRange(n ast.Node) (int, int) {
+	start := n.GetToken().Position.Line
+	end := start
+	ast.Walk(lineRangeVisitor(func(n ast.Node) {
+		tk := n.GetToken()
+		if tk == nil {
+			return
+		}
+		// The origin of the token may span multiple lines, as it happens for block scalars.
+		origin := strings.TrimSpace(tk.Origin)
+		end = max(end, tk.Position.Line+strings.Count(origin, "\n"))
+	}), n)
+	return start, end
+}
+
+type lineRangeVisitor func(n ast.Node)
+
+func (v lineRangeVisitor) Visit(n ast.Node) ast.Visitor {
+	v(n)
+	return v
+}
+
+// alignLines pairs the lines of both sides, where each pair holds the indexes of the left and the right line,
+// or -1 for a missing line. Unchanged lines with the same text are aligned as long as their order allows,
+// and the lines in between are paired in their order.
+func alignLines(leftLines, rightLines []string, leftChanged, rightChanged map[int]bool) [][2]int {
+	leftUnchanged := make([]int, 0, len(leftLines))
+	for i := range leftLines {
+		if !leftChanged[i] {
+			leftUnchanged = append(leftUnchanged, i)
+		}
+	}
+	rightUnchanged := make([]int, 0, len(rightLines))
+	for i := range rightLines {
+		if !rightChanged[i] {
+			rightUnchanged = append(rightUnchanged, i)
+		}
+	}
+
+	matches := longestCommonSubsequence(len(leftUnchanged), len(rightUnchanged), func(l, r int) bool {
+		return leftLines[leftUnchanged[l]] == rightLines[rightUnchanged[r]]
+	})
+	anchors := make([][2]int, 0, len(matches)+1)
+	for l, r := range matches {
+		if r != -1 {
+			anchors = append(anchors, [2]int{leftUnchanged[l], rightUnchanged[r]})
+		}
+	}
+	anchors = append(anchors, [2]int{len(leftLines), len(rightLines)})
+
+	pairs := make([][2]int, 0, max(len(leftLines), len(rightLines)))
+	l, r := 0, 0
+	for _, anchor := range anchors {
+		for l < anchor[0] || r < anchor[1] {
+			pair := [2]int{-1, -1}
+			if l < anchor[0] {
+				pair[0] = l
+				l++
+			}
+			if r < anchor[1] {
+				pair[1] = r
+				r++
+			}
+			pairs = append(pairs, pair)
+		}
+		if anchor[0] < len(leftLines) {
+			pairs = append(pairs, anchor)
+			l++
+			r++
+		}
+	}
+	return pairs
+}
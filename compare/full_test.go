@@ -0,0 +1,65 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatFull(t *testing.T) {
+	left := []byte(`name: Alice
+city: New York
+items:
+  - one
+  - two
+`)
+	right := []byte(`name: Bob
+items:
+  - one
+  - three
+  - four
+value: 990
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output := FormatFull(left, right, diffs, FormatOptions{Plain: true})
+	expected := `name: Alice    | name: Bob
+city: New York <
+items:           items:
+  - one            - one
+  - two        |   - three
+               >   - four
+               > value: 990`
+	assert.Equal(t, expected, output)
+
+	t.Run("no difference", func(t *testing.T) {
+		diffs, err := Compare(left, left, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		output := FormatFull(left, left, diffs, FormatOptions{Plain: true})
+		expected := `name: Alice      name: Alice
+city: New York   city: New York
+items:           items:
+  - one            - one
+  - two            - two`
+		assert.Equal(t, expected, output)
+	})
+
+	t.Run("nested collection", func(t *testing.T) {
+		left := []byte(`a: 1
+b:
+  c: 2
+  d: 3
+`)
+		right := []byte(`a: 1
+`)
+		diffs, err := Compare(left, right, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		output := FormatFull(left, right, diffs, FormatOptions{Plain: true})
+		expected := `a: 1     a: 1
+b:     <
+  c: 2 <
+  d: 3 <`
+		assert.Equal(t, expected, output)
+	})
+}
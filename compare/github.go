@@ -0,0 +1,45 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// FormatGitHub returns the differences as the workflow commands of GitHub Actions that annotate the files,
+// such as "::warning file=deploy.yaml,line=3::~ spec.replicas: 1 -> 2", one per difference,
+// so they are shown on the lines of the files in the pull requests. The deletions refer to the lines of the left file,
+// and the other differences refer to the lines of the right file, which holds their new values.
+// The collections span their lines up to endLine.
+func FormatGitHub(leftName, rightName string, f FileDiffs) string {
+	if !f.HasDiff() {
+		return ""
+	}
+	annotations := make([]string, 0)
+	for _, docDiffs := range f {
+		for _, diff := range docDiffs {
+			filename, node := rightName, diff.rightNode
+			if diff.Type() == Deleted {
+				filename, node = leftName, diff.leftNode
+			}
+			properties := fmt.Sprintf("file=%s,line=%d", escapeGitHubProperty(filename), node.GetToken().Position.Line)
+			if node.Type() == ast.MappingType || node.Type() == ast.SequenceType {
+				start, end := nodeLineRange(node)
+				properties = fmt.Sprintf("file=%s,line=%d,endLine=%d", escapeGitHubProperty(filename), start, end)
+			}
+			annotations = append(annotations, fmt.Sprintf("::warning %s::%s", properties, escapeGitHubData(diff.Format(FormatOptions{Plain: true}))))
+		}
+	}
+	return strings.Join(annotations, "\n")
+}
+
+// escapeGitHubData escapes the message of a workflow command, which ends at the end of the line.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes the value of a property of a workflow command, which also ends at a comma or a colon.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,40 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatGitHub(t *testing.T) {
+	left := []byte(`name: app
+replicas: 1
+debug: true
+`)
+	right := []byte(`name: app
+replicas: 2
+env:
+  LEVEL: info
+  DEBUG: "1"
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	expected := "::warning file=deploy%2Cv2.yaml,line=2::~ replicas: 1 -> 2\n" +
+		"::warning file=deploy.yaml,line=3::- debug: true\n" +
+		"::warning file=deploy%2Cv2.yaml,line=4,endLine=5::+ env: %0A  LEVEL: info%0A  DEBUG: \"1\""
+	assert.Equal(t, expected, FormatGitHub("deploy.yaml", "deploy,v2.yaml", diffs))
+
+	t.Run("different line layouts", func(t *testing.T) {
+		left := []byte("# header\n\nname: app\nport: 80\nitems: [a, b]\ndebug: true\n")
+		right := []byte("port: 81\nitems:\n  - b\n  - a\nname: app\n")
+
+		diffs, err := Compare(left, right, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		expected := "::warning file=right.yaml,line=1::~ port: 80 -> 81\n" +
+			"::warning file=right.yaml,line=3::~ items[0]: a -> b\n" +
+			"::warning file=right.yaml,line=4::~ items[1]: b -> a\n" +
+			"::warning file=left.yaml,line=6::- debug: true"
+		assert.Equal(t, expected, FormatGitHub("left.yaml", "right.yaml", diffs))
+	})
+}
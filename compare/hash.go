@@ -0,0 +1,122 @@
+package compare
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// HashDocument returns a stable content hash of the given node.
+// The hash does not depend on the order of mapping keys or on the formatting of the source, such as the indentation
+// of block scalars, and the scalars are hashed by their values after applying the options that normalize them.
+// Two documents with equal hashes produce no differences, unless CompareComments or DetectKeyOrder is set,
+// as the comments and the order of the keys are not hashed. The converse does not hold:
+// documents that are equal only by CoerceScalarTypes, or by aliases that are expanded, may have different hashes,
+// so the hash can only be used to find equal documents faster, not to tell that they differ.
+func HashDocument(node ast.Node, opts DiffOptions) string {
+	return hex.EncodeToString(nodeHash(node, opts))
+}
+
+func nodeHash(n ast.Node, opts DiffOptions) []byte {
+	h := sha256.New()
+	if n == nil {
+		writeHashField(h, "nil")
+		return h.Sum(nil)
+	}
+
+	if n.Type() == ast.MappingValueType {
+		n = ast.Mapping(n.GetToken(), false, n.(*ast.MappingValueNode))
+	}
+
+	// The scalars whose normalized values are equal are equal regardless of their types.
+	if value, ok := scalarValue(n); ok && len(opts.Normalizers) > 0 {
+		for _, normalizer := range opts.Normalizers {
+			value = normalizer.Normalize(value)
+		}
+		writeHashField(h, "scalar", value)
+		return h.Sum(nil)
+	}
+
+	switch n := n.(type) {
+	case *ast.MappingNode:
+		writeHashField(h, "map")
+		keyValueMap := mappingValueNodesIntoMap(n.Values, opts)
+		keys := make([]string, 0, len(keyValueMap))
+		for k := range keyValueMap {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			writeHashField(h, k)
+			h.Write(nodeHash(keyValueMap[k].Value, opts))
+		}
+	case *ast.SequenceNode:
+		writeHashField(h, "seq")
+		hashes := make([][]byte, 0, len(n.Values))
+		for _, value := range n.Values {
+			hashes = append(hashes, nodeHash(value, opts))
+		}
+		if opts.IgnoreSeqOrder {
+			sort.Slice(hashes, func(i, j int) bool {
+				return bytes.Compare(hashes[i], hashes[j]) < 0
+			})
+		}
+		for _, hash := range hashes {
+			h.Write(hash)
+		}
+	case *ast.StringNode:
+		if opts.CoerceStringNumbers && numericLiteralPattern.MatchString(n.Value) {
+			if strings.ContainsAny(n.Value, ".eE") {
+				f, _ := strconv.ParseFloat(n.Value, 64)
+				writeHashField(h, "float", strconv.FormatFloat(f, 'g', -1, 64))
+			} else {
+				writeHashField(h, "int", n.Value)
+			}
+			break
+		}
+		writeHashField(h, "str", hashedString(n.Value, opts))
+	case *ast.LiteralNode:
+		writeHashField(h, "literal", hashedString(literalValue(n, opts), opts))
+	case *ast.IntegerNode:
+		writeHashField(h, "int", fmt.Sprint(n.Value))
+	case *ast.FloatNode:
+		writeHashField(h, "float", strconv.FormatFloat(n.Value, 'g', -1, 64))
+	case *ast.BoolNode:
+		writeHashField(h, "bool", strconv.FormatBool(n.Value))
+	case *ast.NullNode:
+		writeHashField(h, "null")
+	default:
+		writeHashField(h, n.Type().String(), n.String())
+	}
+	return h.Sum(nil)
+}
+
+// hashedString returns the string value as it is compared, without its surrounding white space
+// if IgnoreScalarWhitespace is set, and in lower case if IgnoreValueCase is set.
+func hashedString(value string, opts DiffOptions) string {
+	if opts.IgnoreScalarWhitespace {
+		value = strings.TrimSpace(value)
+	}
+	if opts.IgnoreValueCase {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// writeHashField writes length-prefixed fields, so that adjacent fields cannot be confused with each other.
+func writeHashField(h hash.Hash, fields ...string) {
+	for _, field := range fields {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(field)))
+		h.Write(size[:])
+		h.Write([]byte(field))
+	}
+}
@@ -0,0 +1,118 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashDocument(t *testing.T) {
+	tests := []struct {
+		left  string
+		right string
+		opts  DiffOptions
+		equal bool
+	}{
+		{
+			left:  "a: 1\nb:\n  c: x\n  d: [1, 2]\n",
+			right: "b: {d: [1, 2], c: x}\na: 1\n",
+			equal: true,
+		},
+		{
+			left:  "a: 1\nb: 2\n",
+			right: "a: 1\nb: 3\n",
+			equal: false,
+		},
+		{
+			left:  "items: [1, 2, 3]\n",
+			right: "items: [3, 2, 1]\n",
+			equal: false,
+		},
+		{
+			left:  "items: [1, 2, 3]\n",
+			right: "items: [3, 2, 1]\n",
+			opts:  DiffOptions{IgnoreSeqOrder: true},
+			equal: true,
+		},
+		{
+			left:  "port: \"8080\"\n",
+			right: "port: 8080\n",
+			equal: false,
+		},
+		{
+			left:  "port: \"8080\"\n",
+			right: "port: 8080\n",
+			opts:  DiffOptions{CoerceStringNumbers: true},
+			equal: true,
+		},
+		{
+			left:  "value: 1\n",
+			right: "value: \"1\"\n",
+			equal: false,
+		},
+		{
+			left:  "script: |\n  echo a\n  echo b\n",
+			right: "script: |\n      echo a\n      echo b\n",
+			equal: true,
+		},
+		{
+			left:  "script: |\n  echo a\n",
+			right: "script: |\n  echo b\n",
+			equal: false,
+		},
+		{
+			left:  "name: Web\n",
+			right: "name: web\n",
+			equal: false,
+		},
+		{
+			left:  "name: Web\n",
+			right: "name: web\n",
+			opts:  DiffOptions{IgnoreValueCase: true},
+			equal: true,
+		},
+		{
+			left:  "name: \" web\"\n",
+			right: "name: web\n",
+			opts:  DiffOptions{IgnoreScalarWhitespace: true},
+			equal: true,
+		},
+		{
+			left:  "enabled: \"yes\"\n",
+			right: "enabled: true\n",
+			opts:  DiffOptions{Normalizers: []ScalarNormalizer{BooleanNormalizer}},
+			equal: true,
+		},
+	}
+
+	for _, test := range tests {
+		leftNode := parseBody(t, test.left)
+		rightNode := parseBody(t, test.right)
+
+		leftHash := HashDocument(leftNode, test.opts)
+		rightHash := HashDocument(rightNode, test.opts)
+		assert.Equal(t, test.equal, leftHash == rightHash, "%q <> %q", test.left, test.right)
+
+		if test.equal {
+			assert.Empty(t, newComparator(test.opts).compareNodes(leftNode, rightNode))
+		}
+	}
+}
+
+func TestHashDocumentStable(t *testing.T) {
+	node := parseBody(t, "a: 1\nb: [x, y]\nc: {d: true, e: null, f: 1.5}\n")
+	hash := HashDocument(node, DefaultDiffOptions)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, hash, HashDocument(node, DefaultDiffOptions))
+	}
+}
+
+func parseBody(t *testing.T, s string) ast.Node {
+	f, err := parser.ParseBytes([]byte(s), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Docs[0].Body
+}
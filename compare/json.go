@@ -0,0 +1,80 @@
+package compare
+
+import (
+	"encoding/json"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// jsonDiff is the representation of a difference in the JSON output.
+type jsonDiff struct {
+	Type     string          `json:"type"`
+	Path     string          `json:"path"`
+	Line     int             `json:"line"`
+	NodeType string          `json:"nodeType"`
+	Value    json.RawMessage `json:"value,omitempty"`
+	OldValue json.RawMessage `json:"oldValue,omitempty"`
+	NewValue json.RawMessage `json:"newValue,omitempty"`
+	From     *int            `json:"from,omitempty"`
+	To       *int            `json:"to,omitempty"`
+	NewPath  string          `json:"newPath,omitempty"`
+}
+
+// MarshalJSON encodes the difference as an object with its type, path, line and node type,
+// along with the value of the added or deleted node, the old and new values of the modified one,
+// the value and the indexes of the moved item as from and to, or the value and the new path of the renamed key.
+// Scalars are encoded as the strings they are written as, and collections as nested JSON values.
+func (d *Diff) MarshalJSON() ([]byte, error) {
+	node := d.rightNode
+	if d.Type() == Deleted {
+		node = d.leftNode
+	}
+	j := jsonDiff{
+		Type:     d.Type().String(),
+		Path:     d.Path(),
+		Line:     node.GetToken().Position.Line,
+		NodeType: node.Type().String(),
+	}
+
+	var err error
+	switch d.Type() {
+	case Added, Deleted:
+		j.Value, err = nodeJSONValue(node)
+	case Moved:
+		j.From, j.To = &d.from, &d.to
+		j.Value, err = nodeJSONValue(node)
+	case Renamed:
+		j.NewPath, _ = d.RenamedPath()
+		j.Value, err = nodeJSONValue(node)
+	case Modified:
+		if j.OldValue, err = nodeJSONValue(d.leftNode); err != nil {
+			return nil, err
+		}
+		j.NewValue, err = nodeJSONValue(d.rightNode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+// FormatJSON returns the differences as a JSON array of documents, each one an array of the differences in it,
+// which are encoded by Diff.MarshalJSON.
+func (d FileDiffs) FormatJSON() ([]byte, error) {
+	docs := make([]DocDiffs, 0, len(d))
+	for _, docDiffs := range d {
+		if docDiffs == nil {
+			docDiffs = DocDiffs{}
+		}
+		docs = append(docs, docDiffs)
+	}
+	return json.Marshal(docs)
+}
+
+// nodeJSONValue returns the scalar value of the node as a JSON string, or the decoded value of the collection as JSON.
+func nodeJSONValue(n ast.Node) (json.RawMessage, error) {
+	if s, ok := scalarValue(n); ok {
+		return json.Marshal(s)
+	}
+	return jsonValue(n)
+}
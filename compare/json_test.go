@@ -0,0 +1,38 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatJSON(t *testing.T) {
+	left := []byte(`name: app
+replicas: 1
+debug: null
+---
+ports: [80]
+`)
+	right := []byte(`name: app
+replicas: "1"
+env:
+  DEBUG: true
+  LEVEL: info
+---
+ports: [80]
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output, err := diffs.FormatJSON()
+	assert.NoError(t, err)
+	expected := `[
+		[
+			{"type": "modified", "path": "replicas", "line": 2, "nodeType": "String", "oldValue": "1", "newValue": "1"},
+			{"type": "deleted", "path": "debug", "line": 3, "nodeType": "Null", "value": null},
+			{"type": "added", "path": "env", "line": 4, "nodeType": "Mapping", "value": {"DEBUG": true, "LEVEL": "info"}}
+		],
+		[]
+	]`
+	assert.JSONEq(t, expected, string(output))
+}
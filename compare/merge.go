@@ -0,0 +1,248 @@
+package compare
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// anchors maps the names of the anchors in a document to the nodes they are defined on.
+// An anchor that is defined more than once maps to its last definition.
+type anchors map[string]ast.Node
+
+// Visit records the anchor definitions while the document is walked.
+func (a anchors) Visit(n ast.Node) ast.Visitor {
+	if anchor, ok := n.(*ast.AnchorNode); ok {
+		a[anchor.Name.GetToken().Value] = anchor.Value
+	}
+	return a
+}
+
+// collectAnchors returns the anchors that are defined under the node, in addition to the shared ones,
+// which the anchors defined under the node override.
+func collectAnchors(n ast.Node, shared anchors) anchors {
+	a := make(anchors, len(shared))
+	maps.Copy(a, shared)
+	if n != nil {
+		ast.Walk(a, n)
+	}
+	return a
+}
+
+// cycle returns the name of an anchor whose value refers to itself through aliases, either directly or via other anchors,
+// or false if there is none, so the aliases can be expanded without looping forever.
+func (a anchors) cycle() (string, bool) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := make(map[string]int)
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		switch states[name] {
+		case visiting:
+			return true
+		case visited:
+			return false
+		}
+		states[name] = visiting
+		for _, alias := range aliasNames(a[name]) {
+			if _, ok := a[alias]; ok && visit(alias) {
+				return true
+			}
+		}
+		states[name] = visited
+		return false
+	}
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if visit(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// aliasNames returns the names of the anchors that the aliases under the node refer to.
+func aliasNames(n ast.Node) []string {
+	var names []string
+	ast.Walk(lineRangeVisitor(func(n ast.Node) {
+		if alias, ok := n.(*ast.AliasNode); ok {
+			names = append(names, alias.Value.GetToken().Value)
+		}
+	}), n)
+	return names
+}
+
+// sharedAnchors returns the anchors of the first document that the later documents refer to, if SharedAnchors is set.
+func sharedAnchors(docs []*ast.DocumentNode, opts DiffOptions) anchors {
+	if !opts.SharedAnchors || len(docs) == 0 {
+		return nil
+	}
+	return collectAnchors(docs[0].Body, nil)
+}
+
+// resolveAnchors collects the anchors of both sides, along with the shared anchors of each side,
+// to resolve the merge keys of their mappings and expand their aliases, if ResolveMergeKeys or ExpandAliases is set.
+// It fails if an alias is to be expanded within the value of its own anchor.
+func (c *comparator) resolveAnchors(leftNode, rightNode ast.Node, leftShared, rightShared anchors) {
+	if !c.opts.ResolveMergeKeys && !c.opts.ExpandAliases {
+		return
+	}
+	c.leftAnchors = collectAnchors(leftNode, leftShared)
+	c.rightAnchors = collectAnchors(rightNode, rightShared)
+	if !c.opts.ExpandAliases {
+		return
+	}
+	for _, a := range []anchors{c.leftAnchors, c.rightAnchors} {
+		if name, ok := a.cycle(); ok {
+			c.fail(fmt.Errorf("cyclic alias *%s at %s", name, GetNodePath(a[name])))
+			return
+		}
+	}
+}
+
+// unwrapAnchor returns the node that the anchor is defined on, if ResolveMergeKeys or ExpandAliases is set,
+// as the anchor is only a label for the aliases that refer to it.
+func (c *comparator) unwrapAnchor(n ast.Node) ast.Node {
+	if anchor, ok := n.(*ast.AnchorNode); ok && (c.opts.ResolveMergeKeys || c.opts.ExpandAliases) {
+		return anchor.Value
+	}
+	return n
+}
+
+// expandAlias returns the node of the anchor that the alias refers to, if ExpandAliases is set.
+// An alias of an unknown anchor is returned as it is.
+func (c *comparator) expandAlias(n ast.Node, anchors anchors) ast.Node {
+	alias, ok := n.(*ast.AliasNode)
+	if !ok || !c.opts.ExpandAliases {
+		return n
+	}
+	if target, ok := anchors[alias.Value.GetToken().Value]; ok {
+		return target
+	}
+	return n
+}
+
+// mappingValues returns the values of the mapping, where the merge keys are replaced by the values of the mappings they merge
+// if ResolveMergeKeys is set. The keys that are defined explicitly override the merged ones, and the keys of the mappings
+// that are merged earlier override the ones that are merged later, so the values hold the effective keys of the mapping.
+// The explicit values come first in their source order, followed by the merged ones.
+func (c *comparator) mappingValues(n *ast.MappingNode, anchors anchors) []*ast.MappingValueNode {
+	return c.mergeMappingValues(n, anchors, make(map[string]bool))
+}
+
+func (c *comparator) mergeMappingValues(n *ast.MappingNode, anchors anchors, visiting map[string]bool) []*ast.MappingValueNode {
+	if !c.opts.ResolveMergeKeys {
+		return n.Values
+	}
+
+	values := make([]*ast.MappingValueNode, 0, len(n.Values))
+	var merged []*ast.MappingValueNode
+	keys := make(map[string]bool)
+	for _, value := range n.Values {
+		if value.Key.Type() == ast.MergeKeyType {
+			merged = append(merged, c.mergedValues(value.Value, anchors, visiting)...)
+			continue
+		}
+		values = append(values, value)
+		keys[mappingKey(value.Key, c.opts)] = true
+	}
+	for _, value := range merged {
+		k := mappingKey(value.Key, c.opts)
+		if keys[k] {
+			continue
+		}
+		values = append(values, value)
+		keys[k] = true
+	}
+	return values
+}
+
+// mergedValues returns the values of the mappings that the value of a merge key refers to,
+// which is either a mapping, an alias of a mapping, or a sequence of them.
+// The aliases that refer to the anchors being merged are skipped, so cyclic merges terminate.
+func (c *comparator) mergedValues(n ast.Node, anchors anchors, visiting map[string]bool) []*ast.MappingValueNode {
+	switch n := n.(type) {
+	case *ast.AliasNode:
+		name := n.Value.GetToken().Value
+		target, ok := anchors[name]
+		if !ok {
+			c.warn("unknown anchor %s of merge key at %s", name, GetNodePath(n))
+			return nil
+		}
+		if visiting[name] {
+			return nil
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+		return c.mergedValues(target, anchors, visiting)
+	case *ast.AnchorNode:
+		return c.mergedValues(n.Value, anchors, visiting)
+	case *ast.MappingValueNode:
+		return c.mergedValues(wrapMappingValueNode(n), anchors, visiting)
+	case *ast.MappingNode:
+		return c.mergeMappingValues(n, anchors, visiting)
+	case *ast.SequenceNode:
+		var values []*ast.MappingValueNode
+		for _, item := range n.Values {
+			values = append(values, c.mergedValues(item, anchors, visiting)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// relabelMergedValue reports the differences under a value that the mapping merges from another mapping
+// under the path of its key in the mapping, rather than in the mapping it is merged from.
+// The differences are relabeled only if they are reported on the side of the mapping, as in relabelPaths.
+func (c *comparator) relabelMergedValue(diffs []*Diff, left bool, mapping *ast.MappingNode, value *ast.MappingValueNode) {
+	if !c.opts.ResolveMergeKeys || len(diffs) == 0 || slices.Contains(mapping.Values, value) {
+		return
+	}
+	from := GetNodePath(value)
+	segment := strings.TrimPrefix(from[len(trimLastPathSegment(from)):], ".")
+	to := GetNodePath(mapping)
+	if to == "$" {
+		to = segment
+	} else {
+		to += "." + segment
+	}
+	relabelPaths(diffs, left, from, to)
+}
+
+// relabelPaths replaces the prefix from of the paths of the differences with to, such as "defaults.image" with "web.image"
+// for from "defaults" and to "web". As the path of a difference is the one of its left node unless it is an addition,
+// only the differences that are reported on the given side are relabeled.
+func relabelPaths(diffs []*Diff, left bool, from, to string) {
+	if from == to {
+		return
+	}
+	for _, d := range diffs {
+		if (d.leftNode != nil) != left {
+			continue
+		}
+		path := d.Path()
+		if path != from && !strings.HasPrefix(path, from+".") && !strings.HasPrefix(path, from+"[") {
+			continue
+		}
+		rest := path[len(from):]
+		if to == "$" {
+			rest = strings.TrimPrefix(rest, ".")
+			if rest == "" {
+				rest = "$"
+			}
+			d.path = rest
+			continue
+		}
+		d.path = to + rest
+	}
+}
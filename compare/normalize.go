@@ -0,0 +1,123 @@
+package compare
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml/ast"
+)
+
+// ScalarNormalizer normalizes the values of scalars before they are compared,
+// so that values which differ only in their notation can be considered equal.
+type ScalarNormalizer interface {
+	// Normalize returns the normalized form of the scalar value as it is written in the document.
+	Normalize(value string) string
+}
+
+// ScalarNormalizerFunc is an adapter to use an ordinary function as a ScalarNormalizer.
+type ScalarNormalizerFunc func(value string) string
+
+// Normalize calls f(value).
+func (f ScalarNormalizerFunc) Normalize(value string) string {
+	return f(value)
+}
+
+var (
+	// TrimNormalizer removes the leading and trailing white space, so " a " and "a" are considered equal.
+	TrimNormalizer ScalarNormalizer = ScalarNormalizerFunc(strings.TrimSpace)
+
+	// CaseNormalizer lowercases the value, so "Alice" and "alice" are considered equal.
+	CaseNormalizer ScalarNormalizer = ScalarNormalizerFunc(strings.ToLower)
+
+	// NumericNormalizer rewrites numbers in their canonical form, so 1, 1.0, 1e0 and 0x1 are considered equal.
+	NumericNormalizer ScalarNormalizer = ScalarNormalizerFunc(normalizeNumeric)
+
+	// BooleanNormalizer rewrites the yes, no, on and off notations of booleans as true and false regardless of their case.
+	BooleanNormalizer ScalarNormalizer = ScalarNormalizerFunc(normalizeBoolean)
+
+	// TimestampNormalizer rewrites timestamps in RFC 3339 format in UTC,
+	// so the same instant written with different time zones or precisions is considered equal.
+	TimestampNormalizer ScalarNormalizer = ScalarNormalizerFunc(normalizeTimestamp)
+)
+
+func normalizeNumeric(value string) string {
+	if i, err := strconv.ParseInt(value, 0, 64); err == nil {
+		return strconv.FormatInt(i, 10)
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return value
+}
+
+func normalizeBoolean(value string) string {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return "true"
+	case "false", "no", "off":
+		return "false"
+	}
+	return value
+}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func normalizeTimestamp(value string) string {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return value
+}
+
+// normalizedEqual reports whether both nodes are scalars whose values are equal after applying the normalizers in order.
+// It is only used to consider more values equal, so the nodes that are equal without normalization stay equal.
+func (c *comparator) normalizedEqual(leftNode, rightNode ast.Node) bool {
+	if len(c.opts.Normalizers) == 0 {
+		return false
+	}
+	leftValue, ok := scalarValue(leftNode)
+	if !ok {
+		return false
+	}
+	rightValue, ok := scalarValue(rightNode)
+	if !ok {
+		return false
+	}
+	for _, normalizer := range c.opts.Normalizers {
+		leftValue = normalizer.Normalize(leftValue)
+		rightValue = normalizer.Normalize(rightValue)
+	}
+	return leftValue == rightValue
+}
+
+// ScalarValue returns the value of the string node as it is written in the document, without quotes,
+// which is the decoded value of a block scalar after applying its folding and chomping indicators,
+// such as "a\nb\n" for "|" followed by the lines "a" and "b". It reports false if the node is not a string or a block scalar.
+func ScalarValue(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.StringNode:
+		return n.Value, true
+	case *ast.LiteralNode:
+		return n.Value.Value, true
+	}
+	return "", false
+}
+
+// scalarValue returns the value of the scalar node as ScalarValue does, where numbers and booleans are returned
+// as they are written as well, such as "0x1F" or "yes".
+func scalarValue(n ast.Node) (string, bool) {
+	switch n.(type) {
+	case *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode:
+		return n.GetToken().Value, true
+	}
+	return ScalarValue(n)
+}
@@ -0,0 +1,101 @@
+package compare
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizers(t *testing.T) {
+	tests := []struct {
+		normalizer ScalarNormalizer
+		value      string
+		expected   string
+	}{
+		{normalizer: TrimNormalizer, value: "  a b ", expected: "a b"},
+		{normalizer: CaseNormalizer, value: "Alice", expected: "alice"},
+		{normalizer: NumericNormalizer, value: "1.0", expected: "1"},
+		{normalizer: NumericNormalizer, value: "0x1F", expected: "31"},
+		{normalizer: NumericNormalizer, value: "1e3", expected: "1000"},
+		{normalizer: NumericNormalizer, value: "v1", expected: "v1"},
+		{normalizer: BooleanNormalizer, value: "Yes", expected: "true"},
+		{normalizer: BooleanNormalizer, value: "off", expected: "false"},
+		{normalizer: BooleanNormalizer, value: "maybe", expected: "maybe"},
+		{normalizer: TimestampNormalizer, value: "2024-01-02T03:04:05+01:00", expected: "2024-01-02T02:04:05Z"},
+		{normalizer: TimestampNormalizer, value: "2024-01-02", expected: "2024-01-02T00:00:00Z"},
+		{normalizer: TimestampNormalizer, value: "yesterday", expected: "yesterday"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.normalizer.Normalize(test.value), test.value)
+	}
+}
+
+func TestCompareNormalizers(t *testing.T) {
+	left := []byte(`
+name: " Alice "
+enabled: yes
+count: 1
+`)
+	right := []byte(`
+name: alice
+enabled: true
+count: 2
+`)
+
+	diffs, err := Compare(left, right, false, DiffOptions{Normalizers: []ScalarNormalizer{TrimNormalizer, CaseNormalizer}})
+	assert.NoError(t, err)
+	assert.Equal(t, "~ enabled: yes -> true\n~ count: 1 -> 2", diffs.Format(FormatOptions{Plain: true}))
+
+	diffs, err = Compare(left, right, false, DiffOptions{Normalizers: []ScalarNormalizer{TrimNormalizer, CaseNormalizer, BooleanNormalizer}})
+	assert.NoError(t, err)
+	assert.Equal(t, "~ count: 1 -> 2", diffs.Format(FormatOptions{Plain: true}))
+
+	t.Run("custom normalizer", func(t *testing.T) {
+		stripVersionPrefix := ScalarNormalizerFunc(func(value string) string {
+			return strings.TrimPrefix(value, "v")
+		})
+		opts := DiffOptions{Normalizers: []ScalarNormalizer{stripVersionPrefix, NumericNormalizer}}
+		diffs, err := Compare([]byte("version: v1.20\n"), []byte("version: 1.2\n"), false, opts)
+		assert.NoError(t, err)
+		assert.Empty(t, diffs[0])
+	})
+}
+
+func TestScalarValue(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		expected string
+		ok       bool
+	}{
+		{yaml: "v: plain", expected: "plain", ok: true},
+		{yaml: `v: "double \"quoted\""`, expected: `double "quoted"`, ok: true},
+		{yaml: "v: 'single ''quoted'''", expected: "single 'quoted'", ok: true},
+		{yaml: "v: |\n  a\n  b\n", expected: "a\nb\n", ok: true},
+		{yaml: "v: |-\n  a\n  b\n", expected: "a\nb", ok: true},
+		{yaml: "v: >-\n  a\n  b\n", expected: "a b", ok: true},
+		{yaml: "v: 0x1F", expected: "", ok: false},
+		{yaml: "v: 1.50", expected: "", ok: false},
+		{yaml: "v: true", expected: "", ok: false},
+		{yaml: "v: null", expected: "", ok: false},
+		{yaml: "v: [a]", expected: "", ok: false},
+	}
+	for _, test := range tests {
+		f, err := parser.ParseBytes([]byte(test.yaml), 0)
+		assert.NoError(t, err)
+		value, ok := ScalarValue(childNode(documentBody(f.Docs[0]), pathSegment{key: "v"}, DefaultDiffOptions))
+		assert.Equal(t, test.ok, ok, test.yaml)
+		assert.Equal(t, test.expected, value, test.yaml)
+	}
+
+	// The comparison reads numbers and booleans as they are written as well.
+	for _, value := range []string{"0x1F", "1.50", "true"} {
+		f, err := parser.ParseBytes([]byte("v: "+value), 0)
+		assert.NoError(t, err)
+		scalar, ok := scalarValue(childNode(documentBody(f.Docs[0]), pathSegment{key: "v"}, DefaultDiffOptions))
+		assert.True(t, ok, value)
+		assert.Equal(t, value, scalar)
+	}
+}
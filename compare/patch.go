@@ -0,0 +1,276 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+)
+
+// patchOperation is an operation of a JSON Patch as defined in RFC 6902.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	From  string          `json:"from,omitempty"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch returns the differences as a JSON Patch, as defined in RFC 6902, that turns the left document into the right one.
+// Additions become add, deletions become remove, modifications become replace and renames become move operations,
+// whose paths are JSON Pointers and whose values are the decoded values of the right nodes.
+// A single document results in a single patch, while multiple documents result in an array of patches, one per document.
+// The patches are only meaningful for the differences of positional comparisons,
+// as the indexes of the items matched regardless of their order do not describe where they move,
+// so the moved items are left out.
+func (d FileDiffs) JSONPatch() ([]byte, error) {
+	patches := make([][]patchOperation, 0, len(d))
+	for _, docDiffs := range d {
+		patch, err := docDiffs.jsonPatch()
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, patch)
+	}
+	if len(patches) == 1 {
+		return json.Marshal(patches[0])
+	}
+	return json.Marshal(patches)
+}
+
+// UndoPatch returns the JSON Patch that turns the right document back into the left one, which reverts the patch
+// that JSONPatch returns. It is the JSON Patch of the differences with their sides swapped,
+// so the additions become remove operations, the deletions become add operations,
+// the modifications replace the values with the ones on the left side and the renames move the keys back.
+func (d FileDiffs) UndoPatch() ([]byte, error) {
+	undo := make(FileDiffs, 0, len(d))
+	for _, docDiffs := range d {
+		reversed := make(DocDiffs, 0, len(docDiffs))
+		for _, diff := range docDiffs {
+			r := *diff
+			r.leftNode, r.rightNode = diff.rightNode, diff.leftNode
+			r.from, r.to = diff.to, diff.from
+			reversed = append(reversed, &r)
+		}
+		undo = append(undo, reversed)
+	}
+	return undo.JSONPatch()
+}
+
+// KubectlPatch returns a kubectl patch command that applies the JSON Patch of the differences to a resource,
+// such as "kubectl patch --type=json -p '[...]'", to which the resource to patch is to be appended, such as "deployment/web".
+// The patch is quoted for POSIX shells. It fails unless the differences are of a single document,
+// as a kubectl patch command applies a single patch.
+func (d FileDiffs) KubectlPatch() (string, error) {
+	if len(d) != 1 {
+		return "", fmt.Errorf("kubectl patch requires a single document, got %d", len(d))
+	}
+	patch, err := d.JSONPatch()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("kubectl patch --type=json -p %s", shellQuote(string(patch))), nil
+}
+
+// shellQuote quotes the string in single quotes for POSIX shells, closing the quotes around each single quote within it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jsonPatch returns the operations in an order in which the index of each one holds when it is applied,
+// as the paths of the deletions and modifications are on the left side, while the paths of the additions are on the right side,
+// which differ when the items of sequences are aligned. The values are replaced and the keys are renamed first,
+// which keeps the indexes of the items, then the items are removed from the last index to the first,
+// and at last they are added from the first index to the last.
+func (d DocDiffs) jsonPatch() ([]patchOperation, error) {
+	patch := make([]patchOperation, 0, len(d))
+	removals := make([]string, 0)
+	additions := make([]*Diff, 0)
+	for _, diff := range d {
+		if diff.comment || diff.keyOrder || diff.moved {
+			continue
+		}
+		path := diff.Path()
+		if newPath, ok := diff.RenamedPath(); ok {
+			// The key is renamed within its mapping on the left side, whose index may differ on the right side.
+			from := pathPointer(path)
+			key := pathPointer(newPath)
+			to := from[:strings.LastIndex(from, "/")] + key[strings.LastIndex(key, "/"):]
+			patch = append(patch, patchOperation{Op: "move", From: from, Path: to})
+			continue
+		}
+		switch diff.Type() {
+		case Deleted:
+			removals = append(removals, path)
+		case Added:
+			additions = append(additions, diff)
+		default:
+			value, err := jsonValue(diff.rightNode)
+			if err != nil {
+				return nil, err
+			}
+			patch = append(patch, patchOperation{Op: "replace", Path: pathPointer(path), Value: value})
+		}
+	}
+
+	sort.SliceStable(removals, func(i, j int) bool {
+		return ComparePaths(removals[i], removals[j]) > 0
+	})
+	for _, path := range removals {
+		patch = append(patch, patchOperation{Op: "remove", Path: pathPointer(path)})
+	}
+	sort.SliceStable(additions, func(i, j int) bool {
+		return ComparePaths(additions[i].Path(), additions[j].Path()) < 0
+	})
+	for _, diff := range additions {
+		value, err := jsonValue(diff.rightNode)
+		if err != nil {
+			return nil, err
+		}
+		patch = append(patch, patchOperation{Op: "add", Path: pathPointer(diff.Path()), Value: value})
+	}
+	return patch, nil
+}
+
+// jsonValue returns the value of the node encoded as JSON.
+func jsonValue(n ast.Node) (json.RawMessage, error) {
+	var v interface{}
+	if err := yaml.NodeToValue(n, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// MergePatch returns the differences as JSON Merge Patches, as defined in RFC 7386, that turn the left documents into the right ones.
+// The patches are written as yaml documents separated by "---", one per document. Deleted keys become null,
+// and the values of added and modified keys are nested under the keys of their parents.
+// As a merge patch cannot address the items of a sequence, a sequence with any difference within it is replaced
+// by its value on the right side as a whole. The differences that only change comments or the order of keys
+// cannot be expressed, so they are left out. It fails if a key holds null on the right side,
+// as a merge patch cannot tell a null value apart from the deletion of the key.
+func (d FileDiffs) MergePatch() ([]byte, error) {
+	var b bytes.Buffer
+	for i, docDiffs := range d {
+		patch, err := docDiffs.mergePatch()
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(patch)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteString("---\n")
+		}
+		b.Write(out)
+	}
+	return b.Bytes(), nil
+}
+
+func (d DocDiffs) mergePatch() (any, error) {
+	var patch any = map[string]any{}
+	for _, diff := range d {
+		if diff.comment || diff.keyOrder {
+			continue
+		}
+		segments := splitPath(diff.Path())
+		if i := slices.IndexFunc(segments, func(s pathSegment) bool { return s.isIndex }); i != -1 {
+			value, err := nodeValue(diff.sequence)
+			if err != nil {
+				return nil, err
+			}
+			setMergePatch(&patch, segments[:i], value)
+			continue
+		}
+		if diff.Type() == Deleted {
+			setMergePatch(&patch, segments, nil)
+			continue
+		}
+		if newPath, ok := diff.RenamedPath(); ok {
+			setMergePatch(&patch, segments, nil)
+			segments = splitPath(newPath)
+		}
+		left, err := nodeValue(diff.leftNode)
+		if err != nil {
+			return nil, err
+		}
+		right, err := nodeValue(diff.rightNode)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := mergePatchValue(left, right)
+		if !ok || (value == nil && len(segments) > 0) {
+			return nil, fmt.Errorf("null value at %s cannot be expressed in a merge patch", diff.Path())
+		}
+		setMergePatch(&patch, segments, value)
+	}
+	return patch, nil
+}
+
+// mergePatchValue returns the merge patch that turns the left value into the right one, which recurses into the keys
+// of the mappings on both sides, such as the ones whose differences are collapsed by MaxDepth, so the deleted keys become null.
+// It reports false if a key holds null on the right side, as the null would delete the key instead.
+func mergePatchValue(left, right any) (any, bool) {
+	rightMap, ok := right.(map[string]any)
+	if !ok {
+		return right, true
+	}
+	leftMap, _ := left.(map[string]any)
+	patch := make(map[string]any)
+	for k := range leftMap {
+		if _, ok := rightMap[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, rightValue := range rightMap {
+		leftValue, ok := leftMap[k]
+		if ok && reflect.DeepEqual(leftValue, rightValue) {
+			continue
+		}
+		if rightValue == nil {
+			return nil, false
+		}
+		value, ok := mergePatchValue(leftValue, rightValue)
+		if !ok {
+			return nil, false
+		}
+		patch[k] = value
+	}
+	return patch, true
+}
+
+// setMergePatch sets the value at the path of the merge patch, adding the mappings of the keys on the way.
+// A value under a path whose value is already set as a whole, such as a replaced sequence, is not set again.
+func setMergePatch(patch *any, segments []pathSegment, value any) {
+	if len(segments) == 0 {
+		*patch = value
+		return
+	}
+	m, ok := (*patch).(map[string]any)
+	if !ok {
+		return
+	}
+	child, ok := m[segments[0].key]
+	if !ok {
+		child = map[string]any{}
+	}
+	setMergePatch(&child, segments[1:], value)
+	m[segments[0].key] = child
+}
+
+// nodeValue returns the decoded value of the node, or nil if there is no node.
+func nodeValue(n ast.Node) (any, error) {
+	if n == nil {
+		return nil, nil
+	}
+	var v any
+	if err := yaml.NodeToValue(n, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,383 @@
+package compare
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPatch(t *testing.T) {
+	left := []byte(`
+metadata:
+  labels:
+    app.kubernetes.io/name: app
+    tier~1: backend
+spec:
+  replicas: 1
+  image: app:v1
+  ports: [80, 443, 8080, 9090]
+`)
+	right := []byte(`
+metadata:
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  replicas: null
+  image: app:v1
+  ports: [80, 443]
+  env:
+    DEBUG: "true"
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	patch, err := diffs.JSONPatch()
+	assert.NoError(t, err)
+	expected := `[
+		{"op": "replace", "path": "/metadata/labels/app.kubernetes.io~1name", "value": "web"},
+		{"op": "replace", "path": "/spec/replicas", "value": null},
+		{"op": "remove", "path": "/spec/ports/3"},
+		{"op": "remove", "path": "/spec/ports/2"},
+		{"op": "remove", "path": "/metadata/labels/tier~01"},
+		{"op": "add", "path": "/spec/env", "value": {"DEBUG": "true"}}
+	]`
+	assert.JSONEq(t, expected, string(patch))
+}
+
+func TestJSONPatchMultipleDocuments(t *testing.T) {
+	left := []byte("a: 1\n---\nb: [x]\n")
+	right := []byte("a: 2\n---\nb: [x, y]\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	patch, err := diffs.JSONPatch()
+	assert.NoError(t, err)
+	expected := `[
+		[{"op": "replace", "path": "/a", "value": 2}],
+		[{"op": "add", "path": "/b/1", "value": "y"}]
+	]`
+	assert.JSONEq(t, expected, string(patch))
+
+	diffs, err = Compare([]byte("a: 1\n"), []byte("a: 1\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	patch, err = diffs.JSONPatch()
+	assert.NoError(t, err)
+	assert.JSONEq(t, "[]", string(patch))
+}
+
+func TestKubectlPatch(t *testing.T) {
+	left := []byte("spec:\n  replicas: 1\n  args: [--name=web]\n")
+	right := []byte("spec:\n  replicas: 2\n  args: [--name=web, --greeting='hi']\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	command, err := diffs.KubectlPatch()
+	assert.NoError(t, err)
+	prefix := "kubectl patch --type=json -p "
+	assert.True(t, strings.HasPrefix(command, prefix), command)
+
+	// The shell removes the quotes around the patch and joins the escaped single quotes back into it.
+	quoted := strings.TrimPrefix(command, prefix)
+	assert.True(t, strings.HasPrefix(quoted, "'") && strings.HasSuffix(quoted, "'"), quoted)
+	body := strings.ReplaceAll(quoted[1:len(quoted)-1], `'\''`, "'")
+	expected := `[
+		{"op": "replace", "path": "/spec/replicas", "value": 2},
+		{"op": "add", "path": "/spec/args/1", "value": "--greeting='hi'"}
+	]`
+	assert.JSONEq(t, expected, body)
+
+	diffs, err = Compare([]byte("a: 1\n---\nb: 1\n"), []byte("a: 2\n---\nb: 2\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	_, err = diffs.KubectlPatch()
+	assert.EqualError(t, err, "kubectl patch requires a single document, got 2")
+}
+
+func TestUndoPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  string
+		right string
+		opts  DiffOptions
+	}{
+		{
+			name:  "nested changes",
+			left:  "metadata:\n  labels:\n    app.kubernetes.io/name: app\n    tier~1: backend\nspec:\n  replicas: 1\n  ports: [80, 443, 8080, 9090]\n",
+			right: "metadata:\n  labels:\n    app.kubernetes.io/name: web\nspec:\n  replicas: null\n  ports: [80, 443]\n  env:\n    DEBUG: \"true\"\n",
+			opts:  DefaultDiffOptions,
+		},
+		{
+			name:  "inserted items",
+			left:  "items: [b, d]\n",
+			right: "items: [a, b, c, d, e]\n",
+			opts:  DefaultDiffOptions,
+		},
+		{
+			name:  "aligned items",
+			left:  "items: [b, x, d, y]\n",
+			right: "items: [a, b, c, d]\n",
+			opts:  DiffOptions{SequenceLCS: true},
+		},
+		{
+			name:  "aligned items on separate lines",
+			left:  "items:\n- b\n- y\n",
+			right: "items:\n- a\n- b\n",
+			opts:  DiffOptions{SequenceLCS: true},
+		},
+		{
+			name:  "changes within shifted items",
+			left:  "items:\n- {n: x}\n- b\n- {n: p, k: 1, old: [1]}\n",
+			right: "items:\n- {n: q}\n- r\n- b\n- {n: p, k: 2, new: [1]}\n",
+			opts:  DiffOptions{SequenceLCS: true, DetectRenames: true},
+		},
+		{
+			name:  "renamed key",
+			left:  "a:\n  old: {x: 1}\n  y: 1\n",
+			right: "a:\n  new: {x: 1}\n  y: 2\n",
+			opts:  DiffOptions{DetectRenames: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs, err := Compare([]byte(test.left), []byte(test.right), false, test.opts)
+			assert.NoError(t, err)
+			patch, err := diffs.JSONPatch()
+			assert.NoError(t, err)
+			undo, err := diffs.UndoPatch()
+			assert.NoError(t, err)
+
+			left := jsonValueOf(t, documentValues(t, []byte(test.left))[0])
+			right := jsonValueOf(t, documentValues(t, []byte(test.right))[0])
+			patched := applyJSONPatch(t, jsonValueOf(t, documentValues(t, []byte(test.left))[0]), patch)
+			assert.Equal(t, right, patched)
+			assert.Equal(t, left, applyJSONPatch(t, patched, undo))
+		})
+	}
+}
+
+// jsonValueOf returns the value as it is decoded from JSON, so the numbers of the yaml and the JSON values are comparable.
+func jsonValueOf(t *testing.T, v any) any {
+	t.Helper()
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	var decoded any
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	return decoded
+}
+
+// applyJSONPatch applies the add, remove, replace and move operations of the JSON Patch to the target as defined in RFC 6902.
+func applyJSONPatch(t *testing.T, target any, patch []byte) any {
+	t.Helper()
+	var operations []struct {
+		Op    string `json:"op"`
+		From  string `json:"from"`
+		Path  string `json:"path"`
+		Value any    `json:"value"`
+	}
+	assert.NoError(t, json.Unmarshal(patch, &operations))
+	for _, operation := range operations {
+		switch operation.Op {
+		case "add", "replace":
+			target = patchPointer(target, pointerTokens(operation.Path), operation.Op, operation.Value)
+		case "remove":
+			target = patchPointer(target, pointerTokens(operation.Path), operation.Op, nil)
+		case "move":
+			value := pointerValue(target, pointerTokens(operation.From))
+			target = patchPointer(target, pointerTokens(operation.From), "remove", nil)
+			target = patchPointer(target, pointerTokens(operation.Path), "add", value)
+		default:
+			t.Fatalf("unexpected operation %q", operation.Op)
+		}
+	}
+	return target
+}
+
+// pointerTokens returns the unescaped reference tokens of the JSON Pointer.
+func pointerTokens(pointer string) []string {
+	if pointer == "" {
+		return nil
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens
+}
+
+func pointerValue(target any, tokens []string) any {
+	for _, token := range tokens {
+		switch v := target.(type) {
+		case map[string]any:
+			target = v[token]
+		case []any:
+			i, _ := strconv.Atoi(token)
+			target = v[i]
+		}
+	}
+	return target
+}
+
+// patchPointer applies the operation to the value at the tokens and returns the updated target.
+func patchPointer(target any, tokens []string, op string, value any) any {
+	if len(tokens) == 0 {
+		return value
+	}
+	switch v := target.(type) {
+	case map[string]any:
+		if len(tokens) > 1 {
+			v[tokens[0]] = patchPointer(v[tokens[0]], tokens[1:], op, value)
+		} else if op == "remove" {
+			delete(v, tokens[0])
+		} else {
+			v[tokens[0]] = value
+		}
+		return v
+	case []any:
+		i := len(v)
+		if tokens[0] != "-" {
+			i, _ = strconv.Atoi(tokens[0])
+		}
+		switch {
+		case len(tokens) > 1:
+			v[i] = patchPointer(v[i], tokens[1:], op, value)
+		case op == "add":
+			v = append(v[:i], append([]any{value}, v[i:]...)...)
+		case op == "remove":
+			v = append(v[:i], v[i+1:]...)
+		default:
+			v[i] = value
+		}
+		return v
+	}
+	return target
+}
+
+// applyMergePatch applies the merge patch to the target as defined in RFC 7386.
+func applyMergePatch(target, patch any) any {
+	patchMap, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]any)
+	if !ok {
+		targetMap = map[string]any{}
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+			continue
+		}
+		targetMap[k] = applyMergePatch(targetMap[k], v)
+	}
+	return targetMap
+}
+
+// documentValues returns the decoded values of the documents of the yaml file.
+func documentValues(t *testing.T, b []byte) []any {
+	t.Helper()
+	f, err := parser.ParseBytes(b, 0)
+	assert.NoError(t, err)
+	values := make([]any, 0, len(f.Docs))
+	for _, doc := range documents(f) {
+		var v any
+		if doc.Body != nil {
+			assert.NoError(t, yaml.NodeToValue(doc.Body, &v))
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestMergePatch(t *testing.T) {
+	maxDepth := 2
+	tests := []struct {
+		name     string
+		left     []byte
+		right    []byte
+		opts     DiffOptions
+		expected string
+	}{
+		{
+			name:     "testdata",
+			left:     readFile(t, fileLeft),
+			right:    readFile(t, fileRight),
+			opts:     DefaultDiffOptions,
+			expected: "city:\n  name: San Francisco\nitem:\n  id: 123\n  price: 10.3\npeople:\n  name: Bob\n  surname: Rose\n",
+		},
+		{
+			name:     "nested changes",
+			left:     []byte("metadata:\n  labels:\n    app.kubernetes.io/name: app\n    tier: backend\nspec:\n  replicas: 1\n  ports: [80, 443]\n  image: app:v1\n"),
+			right:    []byte("metadata:\n  labels:\n    app.kubernetes.io/name: web\nspec:\n  replicas: 1\n  ports: [80]\n  image: app:v1\n  env:\n    DEBUG: \"true\"\n"),
+			opts:     DefaultDiffOptions,
+			expected: "metadata:\n  labels:\n    app.kubernetes.io/name: web\n    tier: null\nspec:\n  env:\n    DEBUG: \"true\"\n  ports:\n  - 80\n",
+		},
+		{
+			name:     "multiple documents",
+			left:     []byte("a: 1\n---\nb: [x, {c: 1}]\n---\nc: 3\n"),
+			right:    []byte("a: 1\n---\nb: [x, {c: 2}]\n---\nd: 3\n"),
+			opts:     DiffOptions{DetectRenames: true},
+			expected: "{}\n---\nb:\n- x\n- c: 2\n---\nc: null\nd: 3\n",
+		},
+		{
+			name:     "replaced documents",
+			left:     []byte("[1, 2]\n---\na: 1\n"),
+			right:    []byte("a: [1, 2]\n---\n"),
+			opts:     DefaultDiffOptions,
+			expected: "a:\n- 1\n- 2\n---\nnull\n",
+		},
+		{
+			name:     "collapsed mappings",
+			left:     []byte("spec:\n  template:\n    image: app:v1\n    debug: true\n    labels:\n      tier: backend\n      app: web\n"),
+			right:    []byte("spec:\n  template:\n    image: app:v2\n    labels:\n      app: web\n"),
+			opts:     DiffOptions{MaxDepth: &maxDepth},
+			expected: "spec:\n  template:\n    debug: null\n    image: app:v2\n    labels:\n      tier: null\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs, err := Compare(test.left, test.right, false, test.opts)
+			assert.NoError(t, err)
+			patch, err := diffs.MergePatch()
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(patch))
+
+			patches := documentValues(t, patch)
+			lefts := documentValues(t, test.left)
+			rights := documentValues(t, test.right)
+			for i := range rights {
+				assert.Equal(t, rights[i], applyMergePatch(lefts[i], patches[i]), i)
+			}
+		})
+	}
+}
+
+func TestMergePatchNull(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  string
+		right string
+	}{
+		{name: "modified to null", left: "a:\n  b: 1\n", right: "a:\n  b: null\n"},
+		{name: "added null", left: "a: {}\n", right: "a:\n  b: null\n"},
+		{name: "added mapping with null", left: "a: 1\n", right: "a: 1\nb:\n  c: ~\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs, err := Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+			assert.NoError(t, err)
+			_, err = diffs.MergePatch()
+			assert.ErrorContains(t, err, "cannot be expressed in a merge patch")
+		})
+	}
+
+	// A null in a replaced sequence, or a document that becomes null, is expressed as it is.
+	diffs, err := Compare([]byte("a: [1]\n---\nb: 1\n"), []byte("a: [1, null]\n---\nnull\n"), false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	patch, err := diffs.MergePatch()
+	assert.NoError(t, err)
+	assert.Equal(t, "a:\n- 1\n- null\n---\nnull\n", string(patch))
+}
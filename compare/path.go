@@ -0,0 +1,143 @@
+package compare
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is a single step of a path, which is either a mapping key or a sequence index.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// splitPath splits the path of a node, such as "spec.containers[0].'app.kubernetes.io/name'", into its segments.
+// Keys that contain special characters are enclosed in single quotes, as goccy/go-yaml renders them in paths.
+func splitPath(path string) []pathSegment {
+	path = strings.TrimPrefix(path, "$")
+	segments := make([]pathSegment, 0)
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end == -1 {
+				end = len(path) - i
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil {
+				segments = append(segments, pathSegment{key: path[i+1 : i+end]})
+			} else {
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			}
+			i += end + 1
+		case '\'':
+			end := strings.IndexByte(path[i+1:], '\'')
+			if end == -1 {
+				end = len(path) - i - 1
+			}
+			segments = append(segments, pathSegment{key: path[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end == -1 {
+				end = len(path) - i
+			}
+			segments = append(segments, pathSegment{key: path[i : i+end]})
+			i += end
+		}
+	}
+	return segments
+}
+
+// ComparePaths compares two paths segment by segment and returns -1, 0 or 1 as the first path sorts before, equal to or after the second.
+// Sequence indexes are compared numerically, so "items[2]" sorts before "items[10]", and a path sorts before the paths under it.
+func ComparePaths(a, b string) int {
+	segmentsA := splitPath(a)
+	segmentsB := splitPath(b)
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		if c := compareSegments(segmentsA[i], segmentsB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(segmentsA) < len(segmentsB):
+		return -1
+	case len(segmentsA) > len(segmentsB):
+		return 1
+	}
+	return 0
+}
+
+func compareSegments(a, b pathSegment) int {
+	switch {
+	case a.isIndex && b.isIndex:
+		return compareInts(a.index, b.index)
+	case a.isIndex:
+		return -1
+	case b.isIndex:
+		return 1
+	}
+	return strings.Compare(a.key, b.key)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// formatPath renders the path in the given style.
+func formatPath(path string, style PathStyle) string {
+	switch style {
+	case PathStylePointer:
+		return pathPointer(path)
+	case PathStyleKubectl:
+		return pathKubectl(path)
+	}
+	return path
+}
+
+// pathPointer converts the path into a JSON Pointer as defined in RFC 6901, such as "/spec/containers/0/image".
+// The root path is converted into the empty string, which points to the whole document.
+func pathPointer(path string) string {
+	var b strings.Builder
+	for _, segment := range splitPath(path) {
+		b.WriteByte('/')
+		if segment.isIndex {
+			b.WriteString(strconv.Itoa(segment.index))
+		} else {
+			b.WriteString(pointerEscaper.Replace(segment.key))
+		}
+	}
+	return b.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// pathKubectl converts the path into the dialect of kubectl field paths, such as ".spec.containers[0].image",
+// where the dots in keys are escaped with a backslash as kubectl does in JSONPath expressions.
+// The root path is converted into ".".
+func pathKubectl(path string) string {
+	segments := splitPath(path)
+	if len(segments) == 0 {
+		return "."
+	}
+	var b strings.Builder
+	for _, segment := range segments {
+		if segment.isIndex {
+			b.WriteString(fmt.Sprintf("[%d]", segment.index))
+		} else {
+			b.WriteByte('.')
+			b.WriteString(strings.ReplaceAll(segment.key, ".", `\.`))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package compare
+
+import (
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected []pathSegment
+	}{
+		{path: "", expected: []pathSegment{}},
+		{path: "$", expected: []pathSegment{}},
+		{path: "a", expected: []pathSegment{{key: "a"}}},
+		{path: "$.a.b", expected: []pathSegment{{key: "a"}, {key: "b"}}},
+		{path: "items[2].name", expected: []pathSegment{{key: "items"}, {index: 2, isIndex: true}, {key: "name"}}},
+		{path: "$[0][1]", expected: []pathSegment{{index: 0, isIndex: true}, {index: 1, isIndex: true}}},
+		{path: "labels.'app.kubernetes.io/name'", expected: []pathSegment{{key: "labels"}, {key: "app.kubernetes.io/name"}}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, splitPath(test.path), test.path)
+	}
+}
+
+func TestComparePaths(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{a: "a", b: "a", expected: 0},
+		{a: "a", b: "b", expected: -1},
+		{a: "b", b: "a", expected: 1},
+		{a: "items[2]", b: "items[10]", expected: -1},
+		{a: "items[10].name", b: "items[9].name", expected: 1},
+		{a: "a", b: "a.b", expected: -1},
+		{a: "a.b", b: "a", expected: 1},
+		{a: "a[0]", b: "a.b", expected: -1},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ComparePaths(test.a, test.b), "%s <> %s", test.a, test.b)
+	}
+}
+
+func TestPathPointer(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "$", expected: ""},
+		{path: "a", expected: "/a"},
+		{path: "a.b[0]", expected: "/a/b/0"},
+		{path: "items[2].name", expected: "/items/2/name"},
+		{path: "$[0][1]", expected: "/0/1"},
+		{path: "labels.'app.kubernetes.io/name'", expected: "/labels/app.kubernetes.io~1name"},
+		{path: "'a~b'", expected: "/a~0b"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, pathPointer(test.path), test.path)
+	}
+}
+
+func TestPathKubectl(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "$", expected: "."},
+		{path: "spec.template.spec.containers[0].image", expected: ".spec.template.spec.containers[0].image"},
+		{path: "$[1].name", expected: "[1].name"},
+		{path: "metadata.labels.'app.kubernetes.io/name'", expected: `.metadata.labels.app\.kubernetes\.io/name`},
+		{path: "data.'config.yaml'", expected: `.data.config\.yaml`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, pathKubectl(test.path), test.path)
+	}
+}
+
+func TestGetNodePath(t *testing.T) {
+	f, err := parser.ParseBytes([]byte("a:\n  items:\n    - x\n    - y\n  m:\n    k: v\n    l: w\n  f: {\"k\": v}\n"), 0)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "$.a.items[1]", expected: "a.items[1]"},
+		{path: "$.a.m", expected: "a.m"},
+		{path: "$.a.m.l", expected: "a.m.l"},
+		{path: "$.a.f", expected: "a.f"},
+		{path: "$.a.f.k", expected: "a.f.k"},
+	}
+	for _, test := range tests {
+		p, err := yaml.PathString(test.path)
+		assert.NoError(t, err)
+		n, err := p.FilterFile(f)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, GetNodePath(n), test.path)
+	}
+	assert.Equal(t, "$", GetNodePath(documentBody(f.Docs[0])))
+}
@@ -0,0 +1,126 @@
+package compare
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// CompareStream compares the yaml files read from the readers one document pair at a time, calling fn with the index
+// and the differences of each pair in their order, so only a single document of each file is held in memory at once.
+// It stops at the first error returned by fn, reading or parsing either of the files, or comparing the documents.
+// The documents are split at the lines starting with "---", and their lines refer to the files as in Compare.
+// As the documents are compared separately, the warnings of the comparison are not reported.
+func CompareStream(left io.Reader, right io.Reader, comments bool, opts DiffOptions, fn func(int, DocDiffs) error) error {
+	var parserMode parser.Mode
+	if comments || opts.CompareComments {
+		parserMode |= parser.ParseComments
+	}
+	// The documents are already compared one by one, so they are not worth comparing in parallel.
+	opts.Jobs = 1
+
+	leftDocs := newDocumentReader(left)
+	rightDocs := newDocumentReader(right)
+	index := 0
+	for {
+		leftAst, err := leftDocs.next(parserMode)
+		if err != nil {
+			return fmt.Errorf("parsing left: %w", err)
+		}
+		rightAst, err := rightDocs.next(parserMode)
+		if err != nil {
+			return fmt.Errorf("parsing right: %w", err)
+		}
+		if leftAst == nil && rightAst == nil {
+			return nil
+		}
+
+		diffs, err := CompareAst(fileOrEmpty(leftAst), fileOrEmpty(rightAst), opts)
+		if err != nil {
+			return err
+		}
+		for _, docDiffs := range diffs {
+			if err := fn(index, docDiffs); err != nil {
+				return err
+			}
+			index++
+		}
+	}
+}
+
+func fileOrEmpty(f *ast.File) *ast.File {
+	if f == nil {
+		return &ast.File{}
+	}
+	return f
+}
+
+// documentReader reads the documents of a yaml file one at a time.
+type documentReader struct {
+	r     *bufio.Reader
+	lines int
+	eof   bool
+	// next holds the line starting the next document, which is read while reading the previous one.
+	nextLine   []byte
+	nextOffset int
+}
+
+func newDocumentReader(r io.Reader) *documentReader {
+	return &documentReader{r: bufio.NewReader(r)}
+}
+
+// next parses the next document, whose lines are shifted by the number of lines before it, or returns nil at the end of the file.
+// The comments, directives and empty lines before a document are read as part of it.
+func (d *documentReader) next(mode parser.Mode) (*ast.File, error) {
+	doc, offset := d.nextLine, d.nextOffset
+	started := doc != nil
+	d.nextLine = nil
+	for !d.eof {
+		line, err := d.r.ReadBytes('\n')
+		if err == io.EOF {
+			d.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		d.lines++
+		if started && isDocumentStart(line) {
+			d.nextLine, d.nextOffset = line, d.lines-1
+			break
+		}
+		doc = append(doc, line...)
+		started = started || isDocumentStart(line) || isDocumentContent(line)
+	}
+	if len(doc) == 0 {
+		return nil, nil
+	}
+	if !utf8.Valid(doc) {
+		return nil, ErrInvalidUTF8
+	}
+
+	tokens := lexer.Tokenize(string(doc))
+	for _, tk := range tokens {
+		tk.Position.Line += offset
+	}
+	return parser.Parse(tokens, mode)
+}
+
+// isDocumentStart reports whether the line is a document start marker, such as "---" or "--- !tag".
+func isDocumentStart(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("---")) && (len(line) == 3 || strings.IndexByte(" \t\r\n", line[3]) >= 0)
+}
+
+// isDocumentContent reports whether the line holds a part of the document other than comments and directives.
+func isDocumentContent(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+	return len(trimmed) > 0 && trimmed[0] != '#' && line[0] != '%'
+}
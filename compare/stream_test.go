@@ -0,0 +1,98 @@
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		left  string
+		right string
+	}{
+		{name: "single document", left: "a: 1\nb: 2\n", right: "a: 2\nc: 3\n"},
+		{name: "multiple documents", left: "a: 1\n---\nb: 2\n---\nc: 3\n", right: "a: 1\n---\nb: 3\n---\nc: 4\n"},
+		{name: "leading marker and comments", left: "# header\n---\na: 1\n--- # second\nb: [1, 2]\n", right: "---\na: 2\n---\nb: [1, 3]\n"},
+		{name: "directive", left: "%YAML 1.2\n---\na: 1\n", right: "a: 2\n"},
+		{name: "more documents on the left", left: "a: 1\n---\nb: 2\n---\nc: 3\n", right: "a: 1\n"},
+		{name: "more documents on the right", left: "a: 1\n", right: "a: 1\n---\nb: 2\n"},
+		{name: "empty documents", left: "---\n---\na: 1\n", right: "---\nb: 1\n---\na: 2\n"},
+		{name: "block scalars", left: "a: |\n  x\n  ---\n---\nb: 1", right: "a: |\n  y\n  ---\n---\nb: 2"},
+		{name: "empty", left: "", right: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := Compare([]byte(test.left), []byte(test.right), false, DefaultDiffOptions)
+			assert.NoError(t, err)
+
+			var diffs FileDiffs
+			err = CompareStream(strings.NewReader(test.left), strings.NewReader(test.right), false, DefaultDiffOptions, func(i int, d DocDiffs) error {
+				assert.Equal(t, len(diffs), i)
+				diffs = append(diffs, d)
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, len(expected), len(diffs))
+			assert.Equal(t, expected.Format(FormatOptions{Plain: true, Metadata: true}), diffs.Format(FormatOptions{Plain: true, Metadata: true}))
+		})
+	}
+
+	t.Run("callback error", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := CompareStream(strings.NewReader("a: 1\n---\nb: 1\n"), strings.NewReader("a: 2\n---\nb: 2\n"), false, DefaultDiffOptions, func(int, DocDiffs) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		err := CompareStream(strings.NewReader("a: 1\n"), strings.NewReader("a: {b: 1\n"), false, DefaultDiffOptions, func(int, DocDiffs) error {
+			return nil
+		})
+		assert.ErrorContains(t, err, "parsing right")
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		err := CompareStream(failingReader{io.ErrUnexpectedEOF}, strings.NewReader("a: 1\n"), false, DefaultDiffOptions, func(int, DocDiffs) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+// multiDocumentYaml returns a file of the given number of small documents, where the document at the changed index,
+// if it is not negative, holds a different value.
+func multiDocumentYaml(n int, changed int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		value := fmt.Sprintf("value-%d", i)
+		if i == changed {
+			value = fmt.Sprintf("changed-%d", i)
+		}
+		fmt.Fprintf(&b, "---\nkind: Service\nmetadata:\n  name: service-%d\nspec:\n  ports: [80, 443]\n  selector:\n    app: %s\n", i, value)
+	}
+	return b.String()
+}
+
+// BenchmarkCompareStream compares files of 5000 documents, which are parsed one at a time.
+func BenchmarkCompareStream(b *testing.B) {
+	left := multiDocumentYaml(5000, -1)
+	right := multiDocumentYaml(5000, 2500)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = CompareStream(strings.NewReader(left), strings.NewReader(right), false, DefaultDiffOptions, func(int, DocDiffs) error {
+			return nil
+		})
+	}
+}
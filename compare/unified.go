@@ -0,0 +1,142 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatUnified renders both yaml files as a single listing in the layout of `diff --unified`,
+// where each line is prefixed by a marker:
+//
+//	' ' the line is not changed
+//	'-' the line exists only on the left side
+//	'+' the line exists only on the right side
+//
+// The lines are aligned as in FormatFull, and the changed lines between two unchanged ones are listed
+// with the left lines first and then the right lines.
+func FormatUnified(left, right []byte, diffs FileDiffs, opts FormatOptions) string {
+	leftLines := splitLines(left)
+	rightLines := splitLines(right)
+	leftChanged := make(map[int]bool)
+	rightChanged := make(map[int]bool)
+	for _, docDiffs := range diffs {
+		for _, diff := range docDiffs {
+			markLines(leftChanged, diff.leftNode)
+			markLines(rightChanged, diff.rightNode)
+		}
+	}
+
+	rows := make([]unifiedRow, 0, len(leftLines)+len(rightLines))
+	var deleted, added []unifiedRow
+	flush := func() {
+		rows = append(rows, deleted...)
+		rows = append(rows, added...)
+		deleted, added = deleted[:0], added[:0]
+	}
+	for _, pair := range alignLines(leftLines, rightLines, leftChanged, rightChanged) {
+		unchanged := pair[0] != -1 && pair[1] != -1 && !leftChanged[pair[0]] && !rightChanged[pair[1]]
+		if unchanged {
+			flush()
+			rows = append(rows, unifiedRow{marker: ' ', text: leftLines[pair[0]], left: pair[0], right: pair[1]})
+			continue
+		}
+		if pair[0] != -1 {
+			deleted = append(deleted, unifiedRow{marker: '-', text: leftLines[pair[0]], left: pair[0], right: -1})
+		}
+		if pair[1] != -1 {
+			added = append(added, unifiedRow{marker: '+', text: rightLines[pair[1]], left: -1, right: pair[1]})
+		}
+	}
+	flush()
+
+	if opts.Context <= 0 {
+		return formatUnifiedRows(rows, opts)
+	}
+	hunks := make([]string, 0)
+	for _, hunk := range unifiedHunks(rows, opts.Context) {
+		header := hunkHeader(rows, hunk[0], hunk[1])
+		if !opts.Plain {
+			header = hiCyanString("%s", header)
+		}
+		hunks = append(hunks, header+"\n"+formatUnifiedRows(rows[hunk[0]:hunk[1]], opts))
+	}
+	return strings.Join(hunks, "\n")
+}
+
+// unifiedRow is a line of the unified output, with the zero-based indexes of the line on each side, or -1 if it is missing.
+type unifiedRow struct {
+	marker byte
+	text   string
+	left   int
+	right  int
+}
+
+func formatUnifiedRows(rows []unifiedRow, opts FormatOptions) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		line := strings.TrimRight(string(row.marker)+row.text, " ")
+		if !opts.Plain {
+			switch row.marker {
+			case '-':
+				line = hiRedString("%s", line)
+			case '+':
+				line = hiGreenString("%s", line)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unifiedHunks returns the ranges of the rows that group the changed rows together with up to the given number
+// of unchanged rows around them, where the groups whose context overlaps or touches are joined,
+// so the rest of the unchanged rows can be left out.
+func unifiedHunks(rows []unifiedRow, context int) [][2]int {
+	hunks := make([][2]int, 0)
+	start, end := -1, -1
+	for i, row := range rows {
+		if row.marker == ' ' {
+			continue
+		}
+		from := max(0, i-context)
+		if start != -1 && from > end {
+			hunks = append(hunks, [2]int{start, end})
+			start = -1
+		}
+		if start == -1 {
+			start = from
+		}
+		end = min(len(rows), i+context+1)
+	}
+	if start != -1 {
+		hunks = append(hunks, [2]int{start, end})
+	}
+	return hunks
+}
+
+// hunkHeader returns the header of the hunk of the rows in the given range in the form of "@@ -l,s +l,s @@",
+// which holds the first line number and the number of lines on each side.
+// A side without lines refers to the line before the hunk, as diff does.
+func hunkHeader(rows []unifiedRow, from, to int) string {
+	leftStart, leftCount := hunkRange(rows, from, to, func(row unifiedRow) int { return row.left })
+	rightStart, rightCount := hunkRange(rows, from, to, func(row unifiedRow) int { return row.right })
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", leftStart, leftCount, rightStart, rightCount)
+}
+
+func hunkRange(rows []unifiedRow, from, to int, line func(unifiedRow) int) (int, int) {
+	before, count := 0, 0
+	for i, row := range rows[:to] {
+		if line(row) == -1 {
+			continue
+		}
+		if i < from {
+			before++
+		} else {
+			count++
+		}
+	}
+	if count == 0 {
+		return before, 0
+	}
+	return before + 1, count
+}
@@ -0,0 +1,105 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatUnified(t *testing.T) {
+	left := []byte(`name: Alice
+city: New York
+items:
+  - one
+  - two
+`)
+	right := []byte(`name: Bob
+items:
+  - one
+  - three
+  - four
+value: 990
+`)
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output := FormatUnified(left, right, diffs, FormatOptions{Plain: true})
+	expected := `-name: Alice
+-city: New York
++name: Bob
+ items:
+   - one
+-  - two
++  - three
++  - four
++value: 990`
+	assert.Equal(t, expected, output)
+
+	t.Run("no difference", func(t *testing.T) {
+		diffs, err := Compare(left, left, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+		output := FormatUnified(left, left, diffs, FormatOptions{Plain: true})
+		expected := ` name: Alice
+ city: New York
+ items:
+   - one
+   - two`
+		assert.Equal(t, expected, output)
+	})
+}
+
+func TestFormatUnifiedContext(t *testing.T) {
+	var leftLines, rightLines []string
+	for i := 1; i <= 50; i++ {
+		leftLines = append(leftLines, fmt.Sprintf("key%d: %d", i, i))
+		rightLines = append(rightLines, fmt.Sprintf("key%d: %d", i, i))
+	}
+	rightLines[24] = "key25: changed"
+	left := []byte(strings.Join(leftLines, "\n") + "\n")
+	right := []byte(strings.Join(rightLines, "\n") + "\n")
+
+	diffs, err := Compare(left, right, false, DefaultDiffOptions)
+	assert.NoError(t, err)
+	output := FormatUnified(left, right, diffs, FormatOptions{Plain: true, Context: 2})
+	expected := `@@ -23,5 +23,5 @@
+ key23: 23
+ key24: 24
+-key25: 25
++key25: changed
+ key26: 26
+ key27: 27`
+	assert.Equal(t, expected, output)
+
+	output = FormatUnified(left, right, diffs, FormatOptions{Plain: true})
+	assert.Len(t, strings.Split(output, "\n"), 51)
+
+	t.Run("separate and joined hunks", func(t *testing.T) {
+		right := []byte(strings.Join(append(append([]string{"key0: 0"}, leftLines[:4]...), "key5: changed"), "\n") + "\n")
+		left := []byte(strings.Join(leftLines[:5], "\n") + "\n")
+		diffs, err := Compare(left, right, false, DefaultDiffOptions)
+		assert.NoError(t, err)
+
+		output := FormatUnified(left, right, diffs, FormatOptions{Plain: true, Context: 1})
+		expected := `@@ -1,1 +1,2 @@
++key0: 0
+ key1: 1
+@@ -4,2 +5,2 @@
+ key4: 4
+-key5: 5
++key5: changed`
+		assert.Equal(t, expected, output)
+
+		output = FormatUnified(left, right, diffs, FormatOptions{Plain: true, Context: 2})
+		expected = `@@ -1,5 +1,6 @@
++key0: 0
+ key1: 1
+ key2: 2
+ key3: 3
+ key4: 4
+-key5: 5
++key5: changed`
+		assert.Equal(t, expected, output)
+	})
+}